@@ -0,0 +1,85 @@
+use std::{
+    env,
+    path::Path,
+    process::Command,
+    time::{SystemTime, UNIX_EPOCH},
+};
+
+// Embeds the commit and build date shown by `--version`. Both can be set from the
+// environment, e.g. by packagers building from a tarball; otherwise they are worked out here.
+fn main() {
+    println!("cargo:rerun-if-env-changed=TAMAMA_GIT_COMMIT");
+    println!("cargo:rerun-if-env-changed=TAMAMA_BUILD_DATE");
+    println!("cargo:rerun-if-env-changed=SOURCE_DATE_EPOCH");
+    watch_git_head();
+
+    let commit = env::var("TAMAMA_GIT_COMMIT")
+        .ok()
+        .or_else(|| git(&["rev-parse", "--short", "HEAD"]))
+        .unwrap_or_else(|| "unknown".to_string());
+    let date = env::var("TAMAMA_BUILD_DATE").unwrap_or_else(|_| build_date());
+
+    println!("cargo:rustc-env=TAMAMA_GIT_COMMIT={}", commit);
+    println!("cargo:rustc-env=TAMAMA_BUILD_DATE={}", date);
+}
+
+// Rebuild when the checked out commit changes. Cargo treats a missing path as always
+// changed, so only existing files are watched; a crates.io tarball has none of them.
+fn watch_git_head() {
+    // A worktree keeps its own HEAD but shares the branches with the main checkout
+    let (Some(git_dir), Some(common_dir)) = (
+        git(&["rev-parse", "--git-dir"]),
+        git(&["rev-parse", "--git-common-dir"]),
+    ) else {
+        return;
+    };
+
+    let watched = [
+        Path::new(&git_dir).join("HEAD"),
+        Path::new(&common_dir).join("refs/heads"),
+        Path::new(&common_dir).join("packed-refs"),
+    ];
+    for path in watched.iter().filter(|path| path.exists()) {
+        println!("cargo:rerun-if-changed={}", path.display());
+    }
+}
+
+// None when git is missing or this is not a git checkout, such as a crates.io build
+fn git(args: &[&str]) -> Option<String> {
+    let output = Command::new("git").args(args).output().ok()?;
+    if !output.status.success() {
+        return None;
+    }
+    let stdout = String::from_utf8(output.stdout).ok()?;
+    Some(stdout.trim().to_string())
+}
+
+// Today in UTC as YYYY-MM-DD, or the day of SOURCE_DATE_EPOCH for reproducible builds
+fn build_date() -> String {
+    let seconds = env::var("SOURCE_DATE_EPOCH")
+        .ok()
+        .and_then(|value| value.parse::<u64>().ok())
+        .unwrap_or_else(|| {
+            SystemTime::now()
+                .duration_since(UNIX_EPOCH)
+                .map_or(0, |elapsed| elapsed.as_secs())
+        });
+
+    // Days since 1970-01-01 to a civil date, after Howard Hinnant's `civil_from_days`
+    let z = seconds / 86_400 + 719_468;
+    let era = z / 146_097;
+    let day_of_era = z - era * 146_097;
+    let year_of_era =
+        (day_of_era - day_of_era / 1_460 + day_of_era / 36_524 - day_of_era / 146_096) / 365;
+    let day_of_year = day_of_era - (365 * year_of_era + year_of_era / 4 - year_of_era / 100);
+    let shifted_month = (5 * day_of_year + 2) / 153;
+    let day = day_of_year - (153 * shifted_month + 2) / 5 + 1;
+    let month = if shifted_month < 10 {
+        shifted_month + 3
+    } else {
+        shifted_month - 9
+    };
+    let year = year_of_era + era * 400 + u64::from(month <= 2);
+
+    format!("{:04}-{:02}-{:02}", year, month, day)
+}
@@ -1,5 +1,25 @@
-use crate::config::Config;
-use rand::{thread_rng, Rng};
+use crate::config::{Config, Edges, Obstacle};
+use rand::Rng;
+
+/// Glyphs used to draw boids, for evenly spaced headings starting east and turning clockwise.
+#[derive(Debug, Clone, PartialEq, Eq, Default)]
+pub enum Charset {
+    #[default]
+    Ascii,
+    Unicode,
+    /// Any number of glyphs; must not be empty.
+    Custom(Vec<char>),
+}
+
+impl Charset {
+    fn glyphs(&self) -> &[char] {
+        match self {
+            Charset::Ascii => &['>', '\\', 'v', '/', '<', '/', '^', '\\'],
+            Charset::Unicode => &['→', '↘', '↓', '↙', '←', '↖', '↑', '↗'],
+            Charset::Custom(glyphs) => glyphs,
+        }
+    }
+}
 
 #[derive(Debug, Clone, Copy)]
 pub struct Vec2 {
@@ -12,16 +32,14 @@ impl Vec2 {
         Self { x: 0.0, y: 0.0 }
     }
 
-    pub fn random(max_x: f32, max_y: f32) -> Self {
-        let mut rng = thread_rng();
+    pub fn random<R: Rng + ?Sized>(max_x: f32, max_y: f32, rng: &mut R) -> Self {
         Self {
             x: rng.gen_range(0.0..max_x),
             y: rng.gen_range(0.0..max_y),
         }
     }
 
-    pub fn random_unit() -> Self {
-        let mut rng = thread_rng();
+    pub fn random_unit<R: Rng + ?Sized>(rng: &mut R) -> Self {
         let angle = rng.gen_range(0.0..std::f32::consts::TAU);
         Self {
             x: angle.cos(),
@@ -115,28 +133,34 @@ impl std::ops::AddAssign for Vec2 {
     }
 }
 
+/// Closest a bouncing boid gets to the canvas edge, in cells.
+pub(crate) const EDGE_MARGIN: f32 = 1.0;
+
 #[derive(Debug, Clone)]
 pub struct Boid {
     pub position: Vec2,
     pub velocity: Vec2,
     pub acceleration: Vec2,
     pub is_leader: bool,
+    /// Fixed offset in `-1.0..1.0` picked at birth, used to vary each boid's color.
+    pub shade: f32,
 }
 
 impl Boid {
-    pub fn new(config: &Config) -> Self {
+    pub fn new<R: Rng + ?Sized>(config: &Config, rng: &mut R) -> Self {
         Self {
-            position: Vec2::random(config.width, config.height),
-            velocity: Vec2::random_unit() * (config.max_speed * 0.5),
+            position: Vec2::random(config.width, config.height, rng),
+            velocity: Vec2::random_unit(rng) * (config.max_speed * 0.5),
             acceleration: Vec2::zero(),
             is_leader: false,
+            shade: rng.gen_range(-1.0..1.0),
         }
     }
 
     pub fn new_leader(config: &Config) -> Self {
         Self {
             position: Vec2 {
-                x: config.width * 0.1,
+                x: config.width * config.patrol_min_x,
                 y: config.height * 0.5,
             },
             velocity: Vec2 {
@@ -145,6 +169,7 @@ impl Boid {
             },
             acceleration: Vec2::zero(),
             is_leader: true,
+            shade: 0.0,
         }
     }
 
@@ -154,15 +179,22 @@ impl Boid {
         self.position += self.velocity;
         self.acceleration = Vec2::zero();
 
-        self.bounce_off_boundaries(config);
+        if !self.is_leader {
+            self.bounce_off_obstacles(&config.obstacles);
+        }
+        // Last, so an obstacle overhanging the edge can't push a boid off the canvas
+        match config.edges {
+            Edges::Bounce => self.bounce_off_boundaries(config),
+            Edges::Wrap => self.wrap_around_boundaries(config),
+        }
     }
 
     pub fn apply_force(&mut self, force: Vec2) {
         self.acceleration += force;
     }
 
-    fn bounce_off_boundaries(&mut self, config: &Config) {
-        let margin = 1.0;
+    pub(crate) fn bounce_off_boundaries(&mut self, config: &Config) {
+        let margin = EDGE_MARGIN;
 
         if self.position.x < margin {
             self.position.x = margin;
@@ -181,30 +213,51 @@ impl Boid {
         }
     }
 
-    pub fn get_direction_char(&self) -> char {
+    fn wrap_around_boundaries(&mut self, config: &Config) {
+        self.position.x = self.position.x.rem_euclid(config.width);
+        self.position.y = self.position.y.rem_euclid(config.height);
+    }
+
+    // Steering normally keeps boids clear; this catches fast boids that still slip inside
+    pub(crate) fn bounce_off_obstacles(&mut self, obstacles: &[Obstacle]) {
+        for obstacle in obstacles {
+            if !obstacle.contains(self.position) {
+                continue;
+            }
+
+            // Leave through the nearest edge
+            let left = self.position.x - obstacle.x;
+            let right = obstacle.x + obstacle.width - self.position.x;
+            let top = self.position.y - obstacle.y;
+            let bottom = obstacle.y + obstacle.height - self.position.y;
+            let nearest = left.min(right).min(top).min(bottom);
+
+            if nearest == left {
+                self.position.x = obstacle.x;
+                self.velocity.x = -self.velocity.x.abs();
+            } else if nearest == right {
+                self.position.x = obstacle.x + obstacle.width;
+                self.velocity.x = self.velocity.x.abs();
+            } else if nearest == top {
+                self.position.y = obstacle.y;
+                self.velocity.y = -self.velocity.y.abs();
+            } else {
+                self.position.y = obstacle.y + obstacle.height;
+                self.velocity.y = self.velocity.y.abs();
+            }
+        }
+    }
+
+    pub fn get_direction_char(&self, charset: &Charset) -> char {
         if self.is_leader {
             return '★';
         }
 
+        // Screen y grows downwards, so positive angles turn clockwise from east
+        let glyphs = charset.glyphs();
         let angle = self.velocity.y.atan2(self.velocity.x);
-        let pi = std::f32::consts::PI;
-
-        if angle >= -pi / 8.0 && angle < pi / 8.0 {
-            '>'
-        } else if angle >= pi / 8.0 && angle < 3.0 * pi / 8.0 {
-            '\\'
-        } else if angle >= 3.0 * pi / 8.0 && angle < 5.0 * pi / 8.0 {
-            'v'
-        } else if angle >= 5.0 * pi / 8.0 && angle < 7.0 * pi / 8.0 {
-            '/'
-        } else if angle >= 7.0 * pi / 8.0 || angle < -7.0 * pi / 8.0 {
-            '<'
-        } else if angle >= -7.0 * pi / 8.0 && angle < -5.0 * pi / 8.0 {
-            '/'
-        } else if angle >= -5.0 * pi / 8.0 && angle < -3.0 * pi / 8.0 {
-            '^'
-        } else {
-            '\\'
-        }
+        let sector_width = std::f32::consts::TAU / glyphs.len() as f32;
+        let sector = (angle / sector_width).round() as i32;
+        glyphs[sector.rem_euclid(glyphs.len() as i32) as usize]
     }
 }
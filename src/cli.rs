@@ -0,0 +1,488 @@
+use std::{
+    fs,
+    path::{Path, PathBuf},
+    time::Duration,
+};
+use tamama::{
+    boid::Charset,
+    color::{parse_palette, Palette},
+    config::{Config, Edges, Obstacle, MAX_DENSITY, MIN_DENSITY},
+};
+
+pub const USAGE: &str = "\
+Usage: tamama [OPTIONS]
+
+Options:
+  --boid-color <COLOR>  Boid color: a name, #rrggbb, or r,g,b [default: green]
+  --palette <NAME>      Boid colors: default, deuteranopia, protanopia or monochrome;
+                        --boid-color overrides it
+  --cycle <SECONDS>     Cycle the boid color through the rainbow once per SECONDS
+  --color-variation <F> Let each boid's brightness vary by up to this fraction (0-1) [default: 0]
+  --bg-color <COLOR>    Background color of the simulation area [default: terminal background]
+  --charset <SET>       Boid glyphs: ascii or unicode arrows [default: ascii]
+  --boid-chars <CHARS>  Custom boid glyphs for evenly spaced headings, starting east and
+                        turning clockwise, e.g. '>v<^'; overrides --charset
+  --braille             Draw boids as smooth Braille dots instead of heading glyphs
+  --list-colors         List the named colors and exit
+  --strict              Exit with an error on invalid colors instead of using defaults
+  --config <FILE>       Read options from a file of `key = value` lines; flags override it
+  --preset <NAME>       Flock style: calm, chaotic, default or swarm; other flags override it
+  --seed <N>            Seed the random generator for a reproducible flock
+  --density <FACTOR>    Scale the number of boids, from 0.25 to 3.0 [default: 1.0]
+  --max-boids <N>       Upper limit on the flock size, including clicked boids [default: 300]
+  --patrol-min-x <F>    Leader's left turnaround point as a fraction of the width [default: 0.1]
+  --patrol-max-x <F>    Leader's right turnaround point as a fraction of the width [default: 0.9]
+  --patrol-amplitude <F>
+                        Leader's sine wave amplitude as a fraction of the height [default: 0.3]
+  --obstacle <X,Y,W,H>  Add a rectangle the flock steers around, in cells from the top-left;
+                        may be repeated
+  --edges <MODE>        What boids do at the canvas edge: bounce or wrap [default: bounce]
+  --backdrop <FILE>     Draw ASCII art from FILE behind the flock, anchored to the bottom
+  --max-speed <N>       Top speed of a boid in cells per frame [default: 1.5]
+  --max-force <N>       Strongest steering change per frame [default: 0.08]
+  --separation <W>      Weight of the rule that keeps boids apart [default: 2.0]
+  --alignment <W>       Weight of the rule that matches neighbours' heading [default: 1.2]
+  --cohesion <W>        Weight of the rule that pulls boids together [default: 1.0]
+  --gravity <F>         Downward pull on the flock as a fraction of the steering force [default: 0]
+  --load <FILE>         Restore a flock saved with the S key
+  --fixed-timestep      Move the flock by elapsed time so slow terminals don't slow it down
+  --duration <TIME>     Quit automatically after e.g. 30s, 500ms or 2m; 0 means no limit
+  --inline              Draw in the current screen instead of the alternate screen
+  --log <FILE>          Write per-frame timings to FILE for diagnosing stutter
+  --record <FILE>       Record the session to an asciinema v2 cast file
+  --frames <N>          Render N frames to stdout without the interactive UI
+  --width <COLS>        Headless frame width [default: terminal width or 80]
+  --height <ROWS>       Headless frame height [default: terminal height or 24]
+  -h, --help            Print this help
+  -V, --version         Print the version, git commit and build date
+";
+
+#[derive(Debug, Default)]
+pub struct Args {
+    pub boid_color: Option<String>,
+    pub palette: Palette,
+    pub cycle: Option<f32>,
+    pub color_variation: f32,
+    pub bg_color: Option<String>,
+    pub backdrop: Option<PathBuf>,
+    pub charset: Charset,
+    pub boid_chars: Option<Charset>,
+    pub braille: bool,
+    pub seed: Option<u64>,
+    pub config: Config,
+    pub config_path: Option<PathBuf>,
+    pub strict: bool,
+    pub list_colors: bool,
+    pub load: Option<PathBuf>,
+    pub fixed_timestep: bool,
+    pub duration: Option<Duration>,
+    pub inline: bool,
+    pub record: Option<PathBuf>,
+    pub log: Option<PathBuf>,
+    pub frames: Option<usize>,
+    pub width: Option<u16>,
+    pub height: Option<u16>,
+    pub help: bool,
+    pub version: bool,
+}
+
+impl Args {
+    pub fn parse() -> Result<Self, String> {
+        let args: Vec<String> = std::env::args().skip(1).collect();
+
+        // Put the file's options first so anything on the command line wins
+        match last_value(&args, "--config") {
+            Some(path) => {
+                let mut file_args = config_file_args(Path::new(&path))?;
+                file_args.extend(args);
+                Self::parse_from(file_args)
+            }
+            None => Self::parse_from(args),
+        }
+    }
+
+    pub fn parse_from<I: IntoIterator<Item = String>>(args: I) -> Result<Self, String> {
+        let mut parsed = Self::default();
+        let args: Vec<String> = args.into_iter().collect();
+
+        // A preset only provides starting values, so apply it before any flag that overrides them
+        if let Some(preset) = last_value(&args, "--preset") {
+            parsed.config.apply_preset(&preset)?;
+        }
+
+        let mut args = args.into_iter();
+
+        while let Some(arg) = args.next() {
+            // Accept both `--flag value` and `--flag=value`
+            let (name, inline_value) = match arg.split_once('=') {
+                Some((name, value)) if name.starts_with("--") => {
+                    (name.to_string(), Some(value.to_string()))
+                }
+                _ => (arg, None),
+            };
+            let mut value = || {
+                inline_value
+                    .clone()
+                    .or_else(|| args.next())
+                    .ok_or_else(|| format!("{} requires a value", name))
+            };
+            // Switches are turned on by their name alone; `--braille=false` must not enable them
+            let switch = || match &inline_value {
+                Some(value) => Err(format!("{} takes no value, got '{}'", name, value)),
+                None => Ok(true),
+            };
+
+            match name.as_str() {
+                "--boid-color" => parsed.boid_color = Some(value()?),
+                "--palette" => parsed.palette = parse_palette(&value()?)?,
+                "--cycle" => parsed.cycle = Some(parse_positive(&name, &value()?)?),
+                "--color-variation" => parsed.color_variation = parse_fraction(&name, &value()?)?,
+                "--bg-color" => parsed.bg_color = Some(value()?),
+                "--preset" => {
+                    value()?;
+                }
+                "--config" => parsed.config_path = Some(PathBuf::from(value()?)),
+                "--seed" => parsed.seed = Some(parse_number(&name, &value()?)?),
+                "--density" => parsed.config.density = parse_density(&name, &value()?)?,
+                "--max-boids" => match parse_number(&name, &value()?)? {
+                    0 => return Err(format!("{} must be at least 1", name)),
+                    max_boids => parsed.config.max_boids = max_boids,
+                },
+                "--patrol-min-x" => parsed.config.patrol_min_x = parse_fraction(&name, &value()?)?,
+                "--patrol-max-x" => parsed.config.patrol_max_x = parse_fraction(&name, &value()?)?,
+                "--patrol-amplitude" => {
+                    parsed.config.patrol_amplitude = parse_fraction(&name, &value()?)?
+                }
+                "--obstacle" => {
+                    let obstacle = parse_obstacle(&name, &value()?)?;
+                    parsed.config.obstacles.push(obstacle)
+                }
+                "--edges" => {
+                    parsed.config.edges = match value()?.as_str() {
+                        "bounce" => Edges::Bounce,
+                        "wrap" => Edges::Wrap,
+                        other => {
+                            return Err(format!(
+                                "unknown edges mode '{}', expected bounce or wrap",
+                                other
+                            ))
+                        }
+                    }
+                }
+                "--charset" => {
+                    parsed.charset = match value()?.as_str() {
+                        "ascii" => Charset::Ascii,
+                        "unicode" => Charset::Unicode,
+                        other => {
+                            return Err(format!(
+                                "unknown charset '{}', expected ascii or unicode",
+                                other
+                            ))
+                        }
+                    }
+                }
+                "--boid-chars" => {
+                    let glyphs: Vec<char> = value()?.chars().collect();
+                    if glyphs.is_empty() {
+                        return Err(format!("{} needs at least one character", name));
+                    }
+                    parsed.boid_chars = Some(Charset::Custom(glyphs));
+                }
+                "--braille" => parsed.braille = switch()?,
+                "--backdrop" => parsed.backdrop = Some(PathBuf::from(value()?)),
+                "--max-speed" => parsed.config.max_speed = parse_positive(&name, &value()?)?,
+                "--max-force" => parsed.config.max_force = parse_positive(&name, &value()?)?,
+                "--separation" => {
+                    parsed.config.separation_weight = parse_non_negative(&name, &value()?)?
+                }
+                "--alignment" => {
+                    parsed.config.alignment_weight = parse_non_negative(&name, &value()?)?
+                }
+                "--cohesion" => {
+                    parsed.config.cohesion_weight = parse_non_negative(&name, &value()?)?
+                }
+                "--gravity" => parsed.config.gravity = parse_fraction(&name, &value()?)?,
+                "--strict" => parsed.strict = switch()?,
+                "--list-colors" => parsed.list_colors = switch()?,
+                "--load" => parsed.load = Some(PathBuf::from(value()?)),
+                "--fixed-timestep" => parsed.fixed_timestep = switch()?,
+                // Zero means no limit, so a config file's duration can be switched off
+                "--duration" => {
+                    parsed.duration = Some(parse_duration(&name, &value()?)?)
+                        .filter(|duration| !duration.is_zero())
+                }
+                "--inline" => parsed.inline = switch()?,
+                "--log" => parsed.log = Some(PathBuf::from(value()?)),
+                "--record" => parsed.record = Some(PathBuf::from(value()?)),
+                "--frames" => parsed.frames = Some(parse_number(&name, &value()?)?),
+                "--width" => parsed.width = Some(parse_number(&name, &value()?)?),
+                "--height" => parsed.height = Some(parse_number(&name, &value()?)?),
+                "-h" | "--help" => parsed.help = switch()?,
+                "-V" | "--version" => parsed.version = switch()?,
+                _ => return Err(format!("unknown argument '{}'", name)),
+            }
+        }
+
+        if let Some(charset) = parsed.boid_chars.take() {
+            parsed.charset = charset;
+        }
+
+        if parsed.config.patrol_min_x >= parsed.config.patrol_max_x {
+            return Err("--patrol-min-x must be less than --patrol-max-x".to_string());
+        }
+
+        Ok(parsed)
+    }
+}
+
+// Finds the value of the last occurrence of `flag`, in either accepted form
+fn last_value(args: &[String], flag: &str) -> Option<String> {
+    let mut found = None;
+    let mut args = args.iter();
+    while let Some(arg) = args.next() {
+        if arg == flag {
+            found = args.next().cloned();
+        } else if let Some(value) = arg.strip_prefix(flag).and_then(|v| v.strip_prefix('=')) {
+            found = Some(value.to_string());
+        }
+    }
+    found
+}
+
+/// Reads a config file and turns it into the equivalent command line flags.
+pub fn config_file_args(path: &Path) -> Result<Vec<String>, String> {
+    let text = fs::read_to_string(path)
+        .map_err(|err| format!("cannot read config {}: {}", path.display(), err))?;
+    config_args(&text).map_err(|err| format!("{}: {}", path.display(), err))
+}
+
+// Config keys that are on/off flags and take `true` or `false`
+const CONFIG_SWITCHES: &[&str] = &[
+    "braille",
+    "strict",
+    "inline",
+    "fixed-timestep",
+    "list-colors",
+];
+
+// A small TOML subset: `key = value` per line, where keys are flag names
+// (`boid-color` or `boid_color`), strings may be quoted and `#` starts a comment
+fn config_args(text: &str) -> Result<Vec<String>, String> {
+    let mut args = Vec::new();
+
+    for (index, line) in text.lines().enumerate() {
+        let line = line.trim();
+        if line.is_empty() || line.starts_with('#') {
+            continue;
+        }
+
+        let invalid = || format!("line {}: expected key = value", index + 1);
+        let (key, value) = line.split_once('=').ok_or_else(invalid)?;
+        let key = key.trim().replace('_', "-");
+        let value = value.trim();
+        // Quoted strings may contain `#`, e.g. hex colors
+        let value = match value.strip_prefix('"') {
+            Some(quoted) => quoted.split_once('"').ok_or_else(invalid)?.0,
+            None => value.split('#').next().unwrap_or_default().trim(),
+        };
+
+        // Only switches become bare flags; anything else keeps its value so the flag's
+        // own parser sees it, rather than `true` taking the next flag as its value
+        match value {
+            "true" if CONFIG_SWITCHES.contains(&key.as_str()) => args.push(format!("--{}", key)),
+            "false" if CONFIG_SWITCHES.contains(&key.as_str()) => {}
+            value => args.push(format!("--{}={}", key, value)),
+        }
+    }
+
+    Ok(args)
+}
+
+fn parse_number<T: std::str::FromStr>(name: &str, value: &str) -> Result<T, String> {
+    value
+        .parse()
+        .map_err(|_| format!("invalid value '{}' for {}", value, name))
+}
+
+fn parse_positive(name: &str, value: &str) -> Result<f32, String> {
+    let number: f32 = parse_number(name, value)?;
+    if number > 0.0 && number.is_finite() {
+        Ok(number)
+    } else {
+        Err(format!(
+            "{} must be a positive number, got '{}'",
+            name, value
+        ))
+    }
+}
+
+fn parse_non_negative(name: &str, value: &str) -> Result<f32, String> {
+    let number: f32 = parse_number(name, value)?;
+    if number >= 0.0 && number.is_finite() {
+        Ok(number)
+    } else {
+        Err(format!("{} must be zero or more, got '{}'", name, value))
+    }
+}
+
+fn parse_fraction(name: &str, value: &str) -> Result<f32, String> {
+    let number: f32 = parse_number(name, value)?;
+    if (0.0..=1.0).contains(&number) {
+        Ok(number)
+    } else {
+        Err(format!("{} must be between 0 and 1, got '{}'", name, value))
+    }
+}
+
+// Same range as the `[`/`]` keys, so they never snap a starting density into range
+fn parse_density(name: &str, value: &str) -> Result<f32, String> {
+    let number: f32 = parse_number(name, value)?;
+    if (MIN_DENSITY..=MAX_DENSITY).contains(&number) {
+        Ok(number)
+    } else {
+        Err(format!(
+            "{} must be between {} and {}, got '{}'",
+            name, MIN_DENSITY, MAX_DENSITY, value
+        ))
+    }
+}
+
+fn parse_obstacle(name: &str, value: &str) -> Result<Obstacle, String> {
+    let invalid = || format!("invalid value '{}' for {}, expected x,y,w,h", value, name);
+    let numbers: Vec<f32> = value
+        .split(',')
+        .map(|n| {
+            n.trim()
+                .parse::<f32>()
+                .ok()
+                .filter(|n| n.is_finite())
+                .ok_or_else(invalid)
+        })
+        .collect::<Result<_, _>>()?;
+
+    match numbers[..] {
+        [x, y, width, height] if x >= 0.0 && y >= 0.0 && width > 0.0 && height > 0.0 => {
+            Ok(Obstacle {
+                x,
+                y,
+                width,
+                height,
+            })
+        }
+        _ => Err(invalid()),
+    }
+}
+
+// Accepts a number with an optional ms, s, m or h suffix; a bare number means seconds
+fn parse_duration(name: &str, value: &str) -> Result<Duration, String> {
+    let split = value
+        .find(|c: char| !c.is_ascii_digit() && c != '.')
+        .unwrap_or(value.len());
+    let (number, unit) = value.split_at(split);
+    let invalid = || format!("invalid duration '{}' for {}", value, name);
+    let number: f64 = number.parse().map_err(|_| invalid())?;
+
+    let seconds = match unit {
+        "ms" => number / 1000.0,
+        "" | "s" => number,
+        "m" => number * 60.0,
+        "h" => number * 3600.0,
+        _ => return Err(invalid()),
+    };
+    Duration::try_from_secs_f64(seconds).map_err(|_| invalid())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn parse(args: &[&str]) -> Result<Args, String> {
+        Args::parse_from(args.iter().map(|arg| arg.to_string()))
+    }
+
+    #[test]
+    fn switches_reject_inline_values() {
+        for arg in ["--braille=false", "--strict=no", "--inline=0", "--help=1"] {
+            assert!(parse(&[arg]).is_err(), "{} was accepted", arg);
+        }
+        assert!(parse(&["--braille"]).unwrap().braille);
+    }
+
+    #[test]
+    fn config_switches_take_true_or_false() {
+        let args = config_args("braille = true\ninline = false\n").unwrap();
+        let parsed = Args::parse_from(args).unwrap();
+        assert!(parsed.braille);
+        assert!(!parsed.inline);
+
+        let args = config_args("inline = 0\n").unwrap();
+        assert!(Args::parse_from(args).is_err());
+
+        // A value flag set to true keeps it as its value instead of taking the next flag's
+        let args = config_args("boid_color = true\ndensity = 2\n").unwrap();
+        let parsed = Args::parse_from(args).unwrap();
+        assert_eq!(parsed.boid_color.as_deref(), Some("true"));
+        assert_eq!(parsed.config.density, 2.0);
+
+        let mut args = config_args("max_boids = true\n").unwrap();
+        args.extend(["--density".to_string(), "2".to_string()]);
+        assert!(Args::parse_from(args).is_err());
+    }
+
+    #[test]
+    fn density_uses_the_same_range_as_the_keys() {
+        assert_eq!(
+            parse(&["--density", "3"]).unwrap().config.density,
+            MAX_DENSITY
+        );
+        assert_eq!(
+            parse(&["--density", "0.25"]).unwrap().config.density,
+            MIN_DENSITY
+        );
+        assert!(parse(&["--density", "5"]).is_err());
+        assert!(parse(&["--density", "0.1"]).is_err());
+    }
+
+    #[test]
+    fn zero_duration_means_no_limit() {
+        for value in ["0", "0s", "0ms"] {
+            assert_eq!(parse(&["--duration", value]).unwrap().duration, None);
+        }
+        assert_eq!(
+            parse(&["--duration", "30s", "--duration", "0"])
+                .unwrap()
+                .duration,
+            None
+        );
+        assert_eq!(
+            parse(&["--duration", "500ms"]).unwrap().duration,
+            Some(Duration::from_millis(500))
+        );
+    }
+
+    #[test]
+    fn obstacles_need_finite_numbers() {
+        for value in ["inf,0,1,1", "0,0,inf,5", "0,NaN,1,1", "0,0,1,infinity"] {
+            assert!(
+                parse(&["--obstacle", value]).is_err(),
+                "{} was accepted",
+                value
+            );
+        }
+        assert_eq!(
+            parse(&["--obstacle", "1,2,3,4"])
+                .unwrap()
+                .config
+                .obstacles
+                .len(),
+            1
+        );
+    }
+
+    #[test]
+    fn flags_accept_separate_and_inline_values() {
+        let parsed = parse(&["--density", "2", "--max-boids=50"]).unwrap();
+        assert_eq!(parsed.config.density, 2.0);
+        assert_eq!(parsed.config.max_boids, 50);
+    }
+}
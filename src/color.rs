@@ -0,0 +1,246 @@
+use ratatui::style::Color;
+
+pub const DEFAULT_BOID_COLOR: Color = Color::Green;
+
+/// Boid colors while running and while paused, picked to stay distinguishable.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct Palette {
+    pub boid: Color,
+    pub paused: Color,
+}
+
+impl Default for Palette {
+    fn default() -> Self {
+        PALETTES[0].1
+    }
+}
+
+// Kept sorted by name after the default; the colorblind palettes use Okabe-Ito colors
+pub const PALETTES: &[(&str, Palette)] = &[
+    (
+        "default",
+        Palette {
+            boid: DEFAULT_BOID_COLOR,
+            paused: Color::Gray,
+        },
+    ),
+    (
+        "deuteranopia",
+        Palette {
+            boid: Color::Rgb(0, 114, 178),
+            paused: Color::Rgb(230, 159, 0),
+        },
+    ),
+    (
+        "monochrome",
+        Palette {
+            boid: Color::White,
+            paused: Color::DarkGray,
+        },
+    ),
+    (
+        "protanopia",
+        Palette {
+            boid: Color::Rgb(86, 180, 233),
+            paused: Color::Rgb(240, 228, 66),
+        },
+    ),
+];
+
+pub fn parse_palette(name: &str) -> Result<Palette, String> {
+    PALETTES
+        .iter()
+        .find(|(n, _)| *n == name)
+        .map(|(_, palette)| *palette)
+        .ok_or_else(|| {
+            let names: Vec<_> = PALETTES.iter().map(|(n, _)| *n).collect();
+            format!(
+                "unknown palette '{}', expected one of: {}",
+                name,
+                names.join(", ")
+            )
+        })
+}
+
+// Kept sorted by name so it can be listed as-is
+pub const NAMED_COLORS: &[(&str, Color)] = &[
+    ("black", Color::Black),
+    ("blue", Color::Blue),
+    ("cyan", Color::Cyan),
+    ("darkgray", Color::DarkGray),
+    ("gray", Color::Gray),
+    ("green", Color::Green),
+    ("lightblue", Color::LightBlue),
+    ("lightcyan", Color::LightCyan),
+    ("lightgreen", Color::LightGreen),
+    ("lightmagenta", Color::LightMagenta),
+    ("lightred", Color::LightRed),
+    ("lightyellow", Color::LightYellow),
+    ("magenta", Color::Magenta),
+    ("red", Color::Red),
+    ("white", Color::White),
+    ("yellow", Color::Yellow),
+];
+
+/// What the terminal can display, following the `NO_COLOR` and `COLORTERM` conventions.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
+pub enum ColorSupport {
+    /// `NO_COLOR` is set: keep the characters, drop every color.
+    None,
+    /// The 256 color palette, used unless `COLORTERM` announces true color.
+    Ansi256,
+    #[default]
+    TrueColor,
+}
+
+impl ColorSupport {
+    pub fn from_env() -> Self {
+        if std::env::var_os("NO_COLOR").is_some_and(|value| !value.is_empty()) {
+            return ColorSupport::None;
+        }
+
+        match std::env::var("COLORTERM").as_deref() {
+            Ok("truecolor") | Ok("24bit") => ColorSupport::TrueColor,
+            _ => ColorSupport::Ansi256,
+        }
+    }
+
+    /// Converts `color` into one the terminal can show.
+    pub fn adapt(self, color: Color) -> Color {
+        match (self, color) {
+            (ColorSupport::None, _) => Color::Reset,
+            (ColorSupport::Ansi256, Color::Rgb(r, g, b)) => Color::Indexed(ansi256(r, g, b)),
+            _ => color,
+        }
+    }
+}
+
+// Nearest entry in the 6x6x6 color cube, whose levels are 0, 95, 135, 175, 215 and 255
+fn ansi256(r: u8, g: u8, b: u8) -> u8 {
+    let level = |c: u8| match c {
+        0..=47 => 0,
+        48..=114 => 1,
+        c => (c - 35) / 40,
+    };
+    16 + 36 * level(r) + 6 * level(g) + level(b)
+}
+
+/// Brightens (`amount > 0`) or darkens an RGB color by a fraction of each channel.
+///
+/// Other colors have no channels to scale and are returned unchanged.
+pub fn shade_color(color: Color, amount: f32) -> Color {
+    match color {
+        Color::Rgb(r, g, b) => {
+            let scale = |c: u8| (c as f32 * (1.0 + amount)).round().clamp(0.0, 255.0) as u8;
+            Color::Rgb(scale(r), scale(g), scale(b))
+        }
+        _ => color,
+    }
+}
+
+/// Fully saturated color for `hue` in degrees, for cycling through the color wheel.
+pub fn hue_color(hue: f32) -> Color {
+    let sector = hue.rem_euclid(360.0) / 60.0;
+    let rising = (255.0 * sector.fract()) as u8;
+    let falling = 255 - rising;
+
+    match sector as u8 {
+        0 => Color::Rgb(255, rising, 0),
+        1 => Color::Rgb(falling, 255, 0),
+        2 => Color::Rgb(0, 255, rising),
+        3 => Color::Rgb(0, falling, 255),
+        4 => Color::Rgb(rising, 0, 255),
+        _ => Color::Rgb(255, 0, falling),
+    }
+}
+
+/// Parses a color given as a name, `#rrggbb`, or `r,g,b`.
+pub fn parse_color(s: &str) -> Result<Color, String> {
+    let s = s.trim();
+    let name = s.to_ascii_lowercase();
+
+    if let Some((_, color)) = NAMED_COLORS.iter().find(|(n, _)| *n == name) {
+        return Ok(*color);
+    }
+
+    if let Some(hex) = s.strip_prefix('#') {
+        if hex.len() == 6 && hex.is_ascii() {
+            let channel = |i: usize| u8::from_str_radix(&hex[i..i + 2], 16);
+            if let (Ok(r), Ok(g), Ok(b)) = (channel(0), channel(2), channel(4)) {
+                return Ok(Color::Rgb(r, g, b));
+            }
+        }
+        return Err(format!("invalid hex color '{}', expected #rrggbb", s));
+    }
+
+    if s.contains(',') {
+        let channels: Vec<_> = s.split(',').map(|c| c.trim().parse::<u8>()).collect();
+        if let [Ok(r), Ok(g), Ok(b)] = channels[..] {
+            return Ok(Color::Rgb(r, g, b));
+        }
+        return Err(format!(
+            "invalid RGB color '{}', expected r,g,b with values 0-255",
+            s
+        ));
+    }
+
+    Err(format!("unknown color '{}'", s))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use rand::{rngs::StdRng, Rng, SeedableRng};
+
+    #[test]
+    fn parse_color_accepts_names_hex_and_rgb() {
+        assert_eq!(parse_color("green"), Ok(Color::Green));
+        assert_eq!(parse_color(" LightBlue "), Ok(Color::LightBlue));
+        assert_eq!(parse_color("#1e90ff"), Ok(Color::Rgb(30, 144, 255)));
+        assert_eq!(parse_color("#1E90FF"), Ok(Color::Rgb(30, 144, 255)));
+        assert_eq!(parse_color("30,144,255"), Ok(Color::Rgb(30, 144, 255)));
+        assert_eq!(parse_color("30, 144, 255"), Ok(Color::Rgb(30, 144, 255)));
+    }
+
+    #[test]
+    fn parse_color_rejects_malformed_input() {
+        for input in [
+            "",
+            " ",
+            "#",
+            "#gggggg",
+            "#12345",
+            "#1234567",
+            "#ééé",
+            "#éééé",
+            "256,0,0",
+            "1,2",
+            "1,2,3,4",
+            "-1,0,0",
+            ",,",
+            "purple",
+            "ñ",
+        ] {
+            assert!(parse_color(input).is_err(), "'{}' was accepted", input);
+        }
+    }
+
+    #[test]
+    fn parse_color_never_panics_on_random_input() {
+        // Weighted towards the characters the parser branches on
+        const ALPHABET: &[char] = &[
+            '#', ',', ' ', '0', '1', '2', '5', '9', 'a', 'f', 'F', 'g', 'x', '+', '-', 'é', '€',
+        ];
+        let mut rng = StdRng::seed_from_u64(65);
+        for _ in 0..10_000 {
+            let len = rng.gen_range(0..12);
+            let input: String = (0..len)
+                .map(|_| match rng.gen_bool(0.9) {
+                    true => ALPHABET[rng.gen_range(0..ALPHABET.len())],
+                    false => rng.gen::<char>(),
+                })
+                .collect();
+            let _ = parse_color(&input);
+        }
+    }
+}
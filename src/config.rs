@@ -1,11 +1,63 @@
+use crate::boid::Vec2;
 use ratatui::layout::Rect;
 
+/// A rectangle the flock steers around, in canvas cells from the top-left corner.
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub struct Obstacle {
+    pub x: f32,
+    pub y: f32,
+    pub width: f32,
+    pub height: f32,
+}
+
+impl Obstacle {
+    pub fn contains(&self, point: Vec2) -> bool {
+        point.x > self.x
+            && point.x < self.x + self.width
+            && point.y > self.y
+            && point.y < self.y + self.height
+    }
+
+    /// The point of the rectangle nearest to `point`, or `point` itself when inside.
+    pub fn closest_point(&self, point: Vec2) -> Vec2 {
+        Vec2 {
+            x: point.x.clamp(self.x, self.x + self.width),
+            y: point.y.clamp(self.y, self.y + self.height),
+        }
+    }
+}
+
+/// What a boid does when it reaches the edge of the canvas.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
+pub enum Edges {
+    #[default]
+    Bounce,
+    /// Leave one side and come back in on the opposite one.
+    Wrap,
+}
+
+/// Range of `Config::density`, shared by `--density` and the `[`/`]` keys.
+pub const MIN_DENSITY: f32 = 0.25;
+pub const MAX_DENSITY: f32 = 3.0;
+
+/// Names accepted by [`Config::apply_preset`].
+pub const PRESETS: &[&str] = &["calm", "chaotic", "default", "swarm"];
+
+#[derive(Debug, Clone)]
 pub struct Config {
     pub width: f32,
     pub height: f32,
     pub num_boids: usize,
+    pub density: f32,
+    pub max_boids: usize,
+    pub patrol_min_x: f32,
+    pub patrol_max_x: f32,
+    pub patrol_amplitude: f32,
     pub max_speed: f32,
     pub max_force: f32,
+    pub gravity: f32,
+    pub obstacles: Vec<Obstacle>,
+    pub edges: Edges,
     pub separation_radius: f32,
     pub alignment_radius: f32,
     pub cohesion_radius: f32,
@@ -15,47 +67,86 @@ pub struct Config {
 }
 
 impl Config {
-    pub fn with_terminal_size(terminal_size: Rect) -> Self {
+    /// Overwrites the flocking tunables with a named bundle of settings.
+    pub fn apply_preset(&mut self, name: &str) -> Result<(), String> {
+        let defaults = Config::default();
+        let (density, max_speed, max_force, separation, alignment, cohesion) = match name {
+            "calm" => (0.75, 1.0, 0.05, 2.5, 1.5, 0.8),
+            "chaotic" => (1.0, 2.5, 0.15, 3.0, 0.3, 0.5),
+            "default" => (
+                defaults.density,
+                defaults.max_speed,
+                defaults.max_force,
+                defaults.separation_weight,
+                defaults.alignment_weight,
+                defaults.cohesion_weight,
+            ),
+            "swarm" => (2.0, 1.8, 0.08, 1.5, 0.8, 1.5),
+            _ => {
+                return Err(format!(
+                    "unknown preset '{}', expected one of: {}",
+                    name,
+                    PRESETS.join(", ")
+                ))
+            }
+        };
+
+        self.density = density;
+        self.max_speed = max_speed;
+        self.max_force = max_force;
+        self.separation_weight = separation;
+        self.alignment_weight = alignment;
+        self.cohesion_weight = cohesion;
+        Ok(())
+    }
+
+    /// Recomputes the canvas size and the values derived from it, keeping every other setting.
+    pub fn fit_to_terminal(&mut self, terminal_size: Rect) {
         // Calculate simulation area (75% for main canvas)
         let canvas_width = (terminal_size.width as f32 * 0.75).max(20.0);
         let canvas_height = (terminal_size.height as f32).max(10.0);
         
-        // Dynamically calculate boid count based on area
         let area = canvas_width * canvas_height;
-        let density_factor = 0.008; // Approximately 1 boid per 125 characters
-        let num_boids = (area * density_factor)
-            .max(15.0)    // Minimum 15 boids
-            .min(100.0)   // Maximum 100 boids
-            as usize;
-        
+        let num_boids = boid_count_for_area(area, self.density, self.max_boids);
+
         // Adjust parameters based on boid density
         let boid_density = num_boids as f32 / area;
         let density_multiplier = (boid_density * 1000.0).max(0.5).min(2.0);
         
-        Self {
-            width: canvas_width,
-            height: canvas_height,
-            num_boids,
-            max_speed: 1.5,
-            max_force: 0.08,
-            separation_radius: 3.0 * density_multiplier,
-            alignment_radius: 5.0,
-            cohesion_radius: 5.0,
-            separation_weight: 2.0,
-            alignment_weight: 1.2,
-            cohesion_weight: 1.0,
-        }
+        self.width = canvas_width;
+        self.height = canvas_height;
+        self.num_boids = num_boids;
+        self.separation_radius = 3.0 * density_multiplier;
     }
 }
 
+/// Number of boids, including the leader, for a canvas of `area` cells.
+///
+/// The base count is one boid per 125 cells, held between 15 and 100 so tiny
+/// and huge terminals still get a sensible flock. `density` then scales it, rounding down,
+/// and the result is kept between 1 (the leader alone) and `max_boids`.
+/// Any non-negative `area` and `density` give a count in that range.
+pub fn boid_count_for_area(area: f32, density: f32, max_boids: usize) -> usize {
+    let base = (area / 125.0).clamp(15.0, 100.0);
+    ((base * density) as usize).clamp(1, max_boids.max(1))
+}
+
 impl Default for Config {
     fn default() -> Self {
         Self {
             width: 80.0,
             height: 24.0,
             num_boids: 25,
+            density: 1.0,
+            max_boids: 300,    // Hard ceiling for clicks and dense flocks
+            patrol_min_x: 0.1, // Leader turns around at 10% and 90% of the width
+            patrol_max_x: 0.9,
+            patrol_amplitude: 0.3, // Sine wave amplitude as a fraction of the height
             max_speed: 1.5,
             max_force: 0.08,
+            gravity: 0.0, // Downward pull as a fraction of max_force
+            obstacles: Vec::new(),
+            edges: Edges::Bounce,
             separation_radius: 3.0,
             alignment_radius: 5.0,
             cohesion_radius: 5.0,
@@ -64,4 +155,40 @@ impl Default for Config {
             cohesion_weight: 1.0,
         }
     }
-}
\ No newline at end of file
+}
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn boid_count_stays_between_one_and_max_boids() {
+        for area in [0.0, 1.0, 2.0, 200.0, 1e9] {
+            for density in [0.0, 0.25, 1.0, 3.0] {
+                for max_boids in [1, 300] {
+                    let count = boid_count_for_area(area, density, max_boids);
+                    assert!(
+                        (1..=max_boids).contains(&count),
+                        "area {} density {} max_boids {} gave {}",
+                        area,
+                        density,
+                        max_boids,
+                        count
+                    );
+                }
+            }
+        }
+    }
+
+    #[test]
+    fn boid_count_scales_base_count_by_density() {
+        // 80x24 is below the 15 boid floor, 150x100 above the 100 boid ceiling
+        assert_eq!(boid_count_for_area(80.0 * 24.0, 1.0, 300), 15);
+        assert_eq!(boid_count_for_area(100.0 * 50.0, 1.0, 300), 40);
+        assert_eq!(boid_count_for_area(150.0 * 100.0, 1.0, 300), 100);
+        // Fractions are dropped as before density existed: a 120x40 terminal gets 28, not 29
+        assert_eq!(boid_count_for_area(90.0 * 40.0, 1.0, 300), 28);
+        assert_eq!(boid_count_for_area(150.0 * 100.0, 0.5, 300), 50);
+        assert_eq!(boid_count_for_area(1e9, 3.0, 300), 300);
+        assert_eq!(boid_count_for_area(1e9, 3.0, 0), 1);
+    }
+}
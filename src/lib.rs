@@ -0,0 +1,11 @@
+//! Boids flocking simulation and its ratatui front end.
+//!
+//! The `tamama` binary is a thin wrapper around [`ui::App`]; other TUIs can
+//! drive a [`simulation::Simulation`] directly or embed the `App` widget.
+
+pub mod boid;
+pub mod color;
+pub mod config;
+pub mod session;
+pub mod simulation;
+pub mod ui;
@@ -1,43 +1,212 @@
-mod boid;
-mod config;
-mod simulation;
-mod ui;
+mod cli;
+mod record;
+mod watch;
 
-use crate::ui::App;
+use crate::cli::{Args, USAGE};
+use crate::record::CastRecorder;
+use crate::watch::FileWatcher;
 use crossterm::{
-    event::{self, DisableMouseCapture, EnableMouseCapture, Event, KeyCode},
+    event::{
+        self, DisableMouseCapture, EnableMouseCapture, Event, KeyCode, KeyModifiers, MouseButton,
+        MouseEventKind,
+    },
     execute,
-    terminal::{disable_raw_mode, enable_raw_mode, EnterAlternateScreen, LeaveAlternateScreen},
+    terminal::{
+        self, disable_raw_mode, enable_raw_mode, EnterAlternateScreen, LeaveAlternateScreen,
+        SetTitle,
+    },
 };
 use ratatui::{
-    backend::CrosstermBackend,
-    Terminal,
+    backend::{Backend, CrosstermBackend, TestBackend},
+    buffer::Buffer,
+    style::Color,
+    Terminal, TerminalOptions, Viewport,
 };
+use signal_hook::consts::{SIGINT, SIGTERM};
 use std::{
     error::Error,
-    io,
-    time::{Duration, Instant},
+    fs::{self, File},
+    io::{self, BufWriter, Write},
+    path::Path,
+    sync::{
+        atomic::{AtomicBool, Ordering},
+        Arc,
+    },
+    time::Instant,
+};
+use tamama::{
+    color::{parse_color, ColorSupport, NAMED_COLORS},
+    session::Session,
+    ui::{App, AppOptions},
 };
 
 fn main() -> Result<(), Box<dyn Error>> {
+    let args = match Args::parse() {
+        Ok(args) => args,
+        Err(err) => {
+            eprintln!("error: {}\n\n{}", err, USAGE);
+            std::process::exit(2);
+        }
+    };
+
+    if args.help {
+        print!("{}", USAGE);
+        return Ok(());
+    }
+
+    if args.version {
+        println!("tamama {}", env!("CARGO_PKG_VERSION"));
+        println!("commit: {}", env!("TAMAMA_GIT_COMMIT"));
+        println!("built:  {}", env!("TAMAMA_BUILD_DATE"));
+        return Ok(());
+    }
+
+    if args.list_colors {
+        for (name, _) in NAMED_COLORS {
+            println!("{}", name);
+        }
+        println!("\nAny #rrggbb hex value or r,g,b triple (0-255) is also accepted.");
+        return Ok(());
+    }
+
+    // The session is only restored at startup, so config reloads never read it again
+    let options = app_options(&args, args.strict).and_then(|options| match &args.load {
+        Some(path) => Ok(AppOptions {
+            session: Some(load_session(path)?),
+            ..options
+        }),
+        None => Ok(options),
+    });
+    let options = match options {
+        Ok(options) => options,
+        Err(err) => {
+            eprintln!("error: {}", err);
+            std::process::exit(2);
+        }
+    };
+
+    if let Some(frames) = args.frames {
+        return run_headless(frames, args.width, args.height, options);
+    }
+
+    match &args.record {
+        Some(path) => {
+            let (width, height) = terminal::size()?;
+            let recorder = CastRecorder::create(io::stdout(), path, width, height)?;
+            run(recorder, options, &args)
+        }
+        None => run(io::stdout(), options, &args),
+    }
+}
+
+// With `strict` an invalid color is an error; otherwise it warns and uses the default
+fn app_options(args: &Args, strict: bool) -> Result<AppOptions, String> {
+    let resolve_color = |value: Option<&str>, default: Color| match value.map(parse_color) {
+        Some(Ok(color)) => Ok(color),
+        Some(Err(err)) if strict => Err(err),
+        Some(Err(err)) => {
+            eprintln!("warning: {}, using default", err);
+            Ok(default)
+        }
+        None => Ok(default),
+    };
+
+    let backdrop = match &args.backdrop {
+        Some(path) => fs::read_to_string(path)
+            .map_err(|err| format!("cannot read backdrop {}: {}", path.display(), err))?
+            .lines()
+            .map(|line| line.trim_end().to_string())
+            .collect(),
+        None => Vec::new(),
+    };
+
+    Ok(AppOptions {
+        boid_color: resolve_color(args.boid_color.as_deref(), args.palette.boid)?,
+        paused_color: args.palette.paused,
+        bg_color: resolve_color(args.bg_color.as_deref(), Color::Reset)?,
+        backdrop,
+        charset: args.charset.clone(),
+        cycle_period: args.cycle,
+        color_variation: args.color_variation,
+        braille: args.braille,
+        fixed_timestep: args.fixed_timestep,
+        headless: false,
+        color_support: ColorSupport::from_env(),
+        session: None,
+        seed: args.seed,
+        config: args.config.clone(),
+    })
+}
+
+fn load_session(path: &Path) -> Result<Session, String> {
+    let text = fs::read_to_string(path)
+        .map_err(|err| format!("cannot read session {}: {}", path.display(), err))?;
+    Session::parse(&text).map_err(|err| format!("{}: {}", path.display(), err))
+}
+
+// xterm's title stack, so the previous window title comes back on exit
+const PUSH_TITLE: &str = "\x1b[22;0t";
+const POP_TITLE: &str = "\x1b[23;0t";
+
+fn run<W: Write>(mut out: W, options: AppOptions, args: &Args) -> Result<(), Box<dyn Error>> {
+    let inline = args.inline;
+    let mut log = match &args.log {
+        Some(path) => {
+            let mut log = BufWriter::new(File::create(path)?);
+            writeln!(log, "# time_s boids update_us render_us")?;
+            Some(log)
+        }
+        None => None,
+    };
+
+    // Quit through the normal path on kill or Ctrl+C from outside, so the terminal is restored
+    let terminate = Arc::new(AtomicBool::new(false));
+    for signal in [SIGTERM, SIGINT] {
+        signal_hook::flag::register(signal, Arc::clone(&terminate))?;
+    }
+
     enable_raw_mode()?;
-    let mut stdout = io::stdout();
-    execute!(stdout, EnterAlternateScreen, EnableMouseCapture)?;
-    let backend = CrosstermBackend::new(stdout);
-    let mut terminal = Terminal::new(backend)?;
+    write!(out, "{}", PUSH_TITLE)?;
+    execute!(out, EnableMouseCapture)?;
+    let mut terminal = if inline {
+        // Draw into the normal screen so the last frame stays in the scrollback
+        let (_, height) = terminal::size()?;
+        let viewport = Viewport::Inline(height);
+        Terminal::with_options(CrosstermBackend::new(out), TerminalOptions { viewport })?
+    } else {
+        execute!(out, EnterAlternateScreen)?;
+        Terminal::new(CrosstermBackend::new(out))?
+    };
 
     // Get terminal size
     let terminal_size = terminal.size()?;
-    let app = App::new(terminal_size);
-    let res = run_app(&mut terminal, app);
+    let app = App::new(terminal_size, options);
+    let quit_at = args.duration.map(|duration| Instant::now() + duration);
+    let watcher = args.config_path.clone().map(FileWatcher::new);
+    let res = run_app(
+        &mut terminal,
+        app,
+        quit_at,
+        &terminate,
+        log.as_mut(),
+        watcher,
+    );
 
     disable_raw_mode()?;
-    execute!(
-        terminal.backend_mut(),
-        LeaveAlternateScreen,
-        DisableMouseCapture
-    )?;
+    if inline {
+        execute!(terminal.backend_mut(), DisableMouseCapture)?;
+    } else {
+        execute!(
+            terminal.backend_mut(),
+            LeaveAlternateScreen,
+            DisableMouseCapture
+        )?;
+    }
+    write!(terminal.backend_mut(), "{}", POP_TITLE)?;
     terminal.show_cursor()?;
+    if let Some(log) = &mut log {
+        log.flush()?;
+    }
 
     if let Err(err) = res {
         println!("{:?}", err)
@@ -46,36 +215,151 @@ fn main() -> Result<(), Box<dyn Error>> {
     Ok(())
 }
 
-fn run_app(
-    terminal: &mut Terminal<CrosstermBackend<std::io::Stdout>>,
+// Clear screen and home the cursor, so piping into a terminal replays the animation
+const FRAME_SEPARATOR: &str = "\x1b[H\x1b[2J";
+
+fn run_headless(
+    frames: usize,
+    width: Option<u16>,
+    height: Option<u16>,
+    options: AppOptions,
+) -> Result<(), Box<dyn Error>> {
+    let (default_width, default_height) = terminal::size().unwrap_or((80, 24));
+    let width = width.unwrap_or(default_width);
+    let height = height.unwrap_or(default_height);
+
+    // Every frame is exactly one tick, however fast they are written, and nothing
+    // shown depends on the wall clock
+    let options = AppOptions {
+        fixed_timestep: false,
+        headless: true,
+        ..options
+    };
+    let mut terminal = Terminal::new(TestBackend::new(width, height))?;
+    let mut app = App::new(terminal.size()?, options);
+    let mut stdout = io::stdout().lock();
+
+    for frame in 0..frames {
+        terminal.draw(|f| app.render(f))?;
+        if frame > 0 {
+            write!(stdout, "{}", FRAME_SEPARATOR)?;
+        }
+        write_buffer(&mut stdout, terminal.backend().buffer())?;
+        app.update();
+    }
+
+    stdout.flush()?;
+    Ok(())
+}
+
+fn write_buffer<W: Write>(out: &mut W, buffer: &Buffer) -> io::Result<()> {
+    let area = buffer.area;
+    for y in area.top()..area.bottom() {
+        let line: String = (area.left()..area.right())
+            .map(|x| buffer.get(x, y).symbol())
+            .collect();
+        writeln!(out, "{}", line.trim_end())?;
+    }
+    Ok(())
+}
+
+fn run_app<B: Backend + Write>(
+    terminal: &mut Terminal<B>,
     mut app: App,
+    quit_at: Option<Instant>,
+    terminate: &AtomicBool,
+    mut log: Option<&mut BufWriter<File>>,
+    mut watcher: Option<FileWatcher>,
 ) -> io::Result<()> {
+    let started = Instant::now();
+    let mut title = String::new();
     loop {
+        if terminate.load(Ordering::Relaxed)
+            || quit_at.is_some_and(|quit_at| Instant::now() >= quit_at)
+        {
+            return Ok(());
+        }
+
+        if watcher.as_mut().is_some_and(|watcher| watcher.changed()) {
+            // Re-read the file together with the original flags; keep the old settings on errors
+            match Args::parse().and_then(|args| app_options(&args, true)) {
+                Ok(options) => {
+                    app.apply_options(options);
+                    app.show_status("Config reloaded".to_string());
+                }
+                Err(err) => app.show_status(format!("Config not reloaded: {}", err)),
+            }
+        }
+
+        let new_title = app.title();
+        if new_title != title {
+            execute!(terminal.backend_mut(), SetTitle(&new_title))?;
+            title = new_title;
+        }
+
+        let render_start = Instant::now();
         terminal.draw(|f| app.render(f))?;
+        let render_time = render_start.elapsed();
 
-        let target_fps = if app.high_fps { 60 } else { 30 };
-        let frame_duration = Duration::from_millis(1000 / target_fps);
+        let frame_duration = app.frame_duration();
         let start_time = Instant::now();
 
         if event::poll(frame_duration)? {
-            if let Event::Key(key) = event::read()? {
-                match key.code {
+            match event::read()? {
+                Event::Key(key) => match key.code {
                     KeyCode::Char('q') => return Ok(()),
-                    KeyCode::Char(' ') => app.toggle_pause(),
+                    // Raw mode delivers Ctrl+C as a key press rather than SIGINT
+                    KeyCode::Char('c') if key.modifiers.contains(KeyModifiers::CONTROL) => {
+                        return Ok(())
+                    }
+                    KeyCode::Char(' ') | KeyCode::Char('p') => app.toggle_pause(),
+                    KeyCode::Char('.') => app.step(),
                     KeyCode::Char('f') => app.toggle_fps(),
+                    KeyCode::Char('+') | KeyCode::Char('=') => app.speed_up(),
+                    KeyCode::Char('-') => app.slow_down(),
+                    KeyCode::Char('w') => app.export_frame(),
+                    KeyCode::Char('s') => app.save_session(),
                     KeyCode::Char('r') => app.reset(),
+                    KeyCode::Char('i') => app.toggle_stats(),
+                    KeyCode::Char('?') => app.toggle_help(),
+                    KeyCode::Left => app.adjust_wind(-0.1),
+                    KeyCode::Right => app.adjust_wind(0.1),
+                    KeyCode::Char('g') => app.toggle_gusts(),
+                    KeyCode::Char('[') => app.adjust_density(-0.25),
+                    KeyCode::Char(']') => app.adjust_density(0.25),
                     _ => {}
+                },
+                Event::Mouse(mouse) => {
+                    if let MouseEventKind::Down(MouseButton::Left) = mouse.kind {
+                        app.click(mouse.column, mouse.row);
+                    }
                 }
+                _ => {}
             }
         }
 
+        let update_start = Instant::now();
         if !app.paused {
             app.update();
         }
+        let update_time = update_start.elapsed();
 
+        if let Some(log) = log.as_deref_mut() {
+            writeln!(
+                log,
+                "{:.3} {} {} {}",
+                started.elapsed().as_secs_f64(),
+                app.simulation.boids.len(),
+                update_time.as_micros(),
+                render_time.as_micros()
+            )?;
+        }
+
+        // While idle the poll above already waited, so don't hold up the next key press
+        let frame_duration = app.frame_duration();
         let elapsed = start_time.elapsed();
-        if elapsed < frame_duration {
+        if !app.is_idle() && elapsed < frame_duration {
             std::thread::sleep(frame_duration - elapsed);
         }
     }
-}
\ No newline at end of file
+}
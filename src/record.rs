@@ -0,0 +1,92 @@
+use std::{
+    fs::File,
+    io::{self, BufWriter, Write},
+    path::Path,
+    time::{Instant, SystemTime, UNIX_EPOCH},
+};
+
+/// Tees everything written to the terminal into an asciinema v2 cast file.
+///
+/// Output is buffered until the backend flushes, so each rendered frame
+/// becomes a single `[time, "o", data]` event.
+pub struct CastRecorder<W: Write> {
+    inner: W,
+    file: BufWriter<File>,
+    pending: Vec<u8>,
+    start: Instant,
+}
+
+impl<W: Write> CastRecorder<W> {
+    pub fn create(inner: W, path: &Path, width: u16, height: u16) -> io::Result<Self> {
+        let mut file = BufWriter::new(File::create(path)?);
+        let timestamp = SystemTime::now()
+            .duration_since(UNIX_EPOCH)
+            .map(|d| d.as_secs())
+            .unwrap_or_default();
+        writeln!(
+            file,
+            r#"{{"version": 2, "width": {}, "height": {}, "timestamp": {}}}"#,
+            width, height, timestamp
+        )?;
+
+        Ok(Self {
+            inner,
+            file,
+            pending: Vec::new(),
+            start: Instant::now(),
+        })
+    }
+
+    fn record_pending(&mut self) -> io::Result<()> {
+        if self.pending.is_empty() {
+            return Ok(());
+        }
+
+        let data = String::from_utf8_lossy(&self.pending);
+        writeln!(
+            self.file,
+            r#"[{:.6}, "o", "{}"]"#,
+            self.start.elapsed().as_secs_f64(),
+            escape_json(&data)
+        )?;
+        self.pending.clear();
+        Ok(())
+    }
+}
+
+impl<W: Write> Write for CastRecorder<W> {
+    fn write(&mut self, buf: &[u8]) -> io::Result<usize> {
+        let written = self.inner.write(buf)?;
+        self.pending.extend_from_slice(&buf[..written]);
+        Ok(written)
+    }
+
+    fn flush(&mut self) -> io::Result<()> {
+        self.inner.flush()?;
+        self.record_pending()
+    }
+}
+
+impl<W: Write> Drop for CastRecorder<W> {
+    fn drop(&mut self) {
+        // Nothing useful to do with errors this late; the terminal is already restored
+        let _ = self.record_pending();
+        let _ = self.file.flush();
+    }
+}
+
+fn escape_json(s: &str) -> String {
+    let mut escaped = String::with_capacity(s.len());
+    for c in s.chars() {
+        match c {
+            '"' => escaped.push_str("\\\""),
+            '\\' => escaped.push_str("\\\\"),
+            '\n' => escaped.push_str("\\n"),
+            '\r' => escaped.push_str("\\r"),
+            '\t' => escaped.push_str("\\t"),
+            c if (c as u32) < 0x20 => escaped.push_str(&format!("\\u{:04x}", c as u32)),
+            c => escaped.push(c),
+        }
+    }
+    escaped
+}
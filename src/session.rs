@@ -0,0 +1,91 @@
+use crate::boid::Vec2;
+
+/// A saved flock: the wind plus every follower's position and velocity.
+///
+/// Stored as plain text, one `wind` line and one `boid x y vx vy` line per
+/// follower. The leader and gusts are not saved; they restart fresh.
+#[derive(Debug, Clone, Default)]
+pub struct Session {
+    pub wind_x: f32,
+    pub boids: Vec<(Vec2, Vec2)>,
+}
+
+impl Session {
+    pub fn parse(text: &str) -> Result<Self, String> {
+        let mut session = Session::default();
+
+        for (index, line) in text.lines().enumerate() {
+            let line = line.trim();
+            if line.is_empty() || line.starts_with('#') {
+                continue;
+            }
+
+            let invalid = || format!("line {}: invalid session entry '{}'", index + 1, line);
+            let mut fields = line.split_whitespace();
+            let kind = fields.next().unwrap_or_default();
+            // NaN or infinity would spread to the whole flock through the flocking rules
+            let numbers: Vec<f32> = fields
+                .map(|n| {
+                    n.parse::<f32>()
+                        .ok()
+                        .filter(|n| n.is_finite())
+                        .ok_or_else(invalid)
+                })
+                .collect::<Result<_, _>>()?;
+
+            match (kind, &numbers[..]) {
+                ("wind", &[wind_x]) => session.wind_x = wind_x.clamp(-1.0, 1.0),
+                ("boid", &[x, y, vx, vy]) => {
+                    session.boids.push((Vec2 { x, y }, Vec2 { x: vx, y: vy }))
+                }
+                _ => return Err(invalid()),
+            }
+        }
+
+        Ok(session)
+    }
+
+    pub fn to_text(&self) -> String {
+        let mut text = format!("# tamama session\nwind {}\n", self.wind_x);
+        for (position, velocity) in &self.boids {
+            text.push_str(&format!(
+                "boid {} {} {} {}\n",
+                position.x, position.y, velocity.x, velocity.y
+            ));
+        }
+        text
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parse_reads_back_to_text() {
+        let session = Session {
+            wind_x: -0.5,
+            boids: vec![(Vec2 { x: 3.0, y: 4.5 }, Vec2 { x: -1.0, y: 0.25 })],
+        };
+        let parsed = Session::parse(&session.to_text()).unwrap();
+
+        assert_eq!(parsed.wind_x, -0.5);
+        let (position, velocity) = parsed.boids[0];
+        assert_eq!((position.x, position.y), (3.0, 4.5));
+        assert_eq!((velocity.x, velocity.y), (-1.0, 0.25));
+    }
+
+    #[test]
+    fn parse_rejects_non_finite_numbers() {
+        for text in ["wind NaN", "wind inf", "boid 1 2 NaN 0", "boid -inf 2 0 0"] {
+            let err = Session::parse(text).err();
+            assert!(
+                err.as_deref()
+                    .is_some_and(|err| err.contains("invalid session entry")),
+                "'{}' gave {:?}",
+                text,
+                err
+            );
+        }
+    }
+}
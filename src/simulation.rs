@@ -1,5 +1,7 @@
-use crate::boid::{Boid, Vec2};
+use crate::boid::{Boid, Charset, Vec2, EDGE_MARGIN};
 use crate::config::Config;
+use crate::session::Session;
+use rand::{rngs::StdRng, Rng, SeedableRng};
 use ratatui::layout::Rect;
 
 #[derive(Debug, Clone, Copy)]
@@ -22,24 +24,46 @@ impl LeaderBird {
         Self {
             boid_index,
             direction: PatrolDirection::ToRight,
-            target_x: config.width * 0.9,
+            target_x: turnaround_x(config, PatrolDirection::ToRight),
             sine_time: 0.0,
-            sine_frequency: 0.02,                // Sine wave frequency
-            sine_amplitude: config.height * 0.3, // Sine wave amplitude
+            sine_frequency: 0.02, // Sine wave frequency
+            sine_amplitude: config.height * config.patrol_amplitude, // Sine wave amplitude
         }
     }
 }
 
+/// Where the leader turns around when heading in `direction`.
+///
+/// Kept inside the edge margin, or a leader patrolling to 0 or 1 would never reach it.
+fn turnaround_x(config: &Config, direction: PatrolDirection) -> f32 {
+    let fraction = match direction {
+        PatrolDirection::ToRight => config.patrol_max_x,
+        PatrolDirection::ToLeft => config.patrol_min_x,
+    };
+    (config.width * fraction).clamp(EDGE_MARGIN, (config.width - EDGE_MARGIN).max(EDGE_MARGIN))
+}
+
 pub struct Simulation {
     pub boids: Vec<Boid>,
     pub config: Config,
     pub leader: Option<LeaderBird>,
+    pub wind_x: f32,
+    pub gusts: bool,
+    gust: f32,
+    gust_target: f32,
+    rng: StdRng,
+}
+
+impl Default for Simulation {
+    fn default() -> Self {
+        Self::new()
+    }
 }
 
 impl Simulation {
-    #[allow(dead_code)]
     pub fn new() -> Self {
         let config = Config::default();
+        let mut rng = StdRng::from_entropy();
         let mut boids = Vec::new();
 
         // Create leader bird
@@ -47,7 +71,7 @@ impl Simulation {
 
         // Create other boids
         for _ in 1..config.num_boids {
-            boids.push(Boid::new(&config));
+            boids.push(Boid::new(&config, &mut rng));
         }
 
         let leader = Some(LeaderBird::new(0, &config));
@@ -56,11 +80,21 @@ impl Simulation {
             boids,
             config,
             leader,
+            wind_x: 0.0,
+            gusts: false,
+            gust: 0.0,
+            gust_target: 0.0,
+            rng,
         }
     }
 
-    pub fn new_with_size(terminal_size: Rect) -> Self {
-        let config = Config::with_terminal_size(terminal_size);
+    /// Creates a simulation from `config` sized to the terminal. A `seed` makes the run reproducible.
+    pub fn new_with_size(terminal_size: Rect, seed: Option<u64>, mut config: Config) -> Self {
+        config.fit_to_terminal(terminal_size);
+        let mut rng = match seed {
+            Some(seed) => StdRng::seed_from_u64(seed),
+            None => StdRng::from_entropy(),
+        };
         let mut boids = Vec::new();
 
         // Create leader bird
@@ -68,7 +102,7 @@ impl Simulation {
 
         // Create other boids
         for _ in 1..config.num_boids {
-            boids.push(Boid::new(&config));
+            boids.push(Boid::new(&config, &mut rng));
         }
 
         let leader = Some(LeaderBird::new(0, &config));
@@ -77,12 +111,18 @@ impl Simulation {
             boids,
             config,
             leader,
+            wind_x: 0.0,
+            gusts: false,
+            gust: 0.0,
+            gust_target: 0.0,
+            rng,
         }
     }
 
     pub fn update(&mut self) {
         // Update leader logic
         self.update_leader_state();
+        self.update_wind();
 
         let mut forces = Vec::new();
 
@@ -97,11 +137,17 @@ impl Simulation {
                 let alignment = self.alignment(i);
                 let cohesion = self.cohesion(i);
                 let follow_leader = self.follow_leader_force(i);
+                let wind = self.wind_force();
+                let gravity = self.gravity_force();
+                let avoid = self.obstacle_force(i);
 
                 let total_force = separation * self.config.separation_weight
                     + alignment * self.config.alignment_weight
                     + cohesion * self.config.cohesion_weight
-                    + follow_leader * 1.5; // Higher weight for following leader
+                    + follow_leader * 1.5 // Higher weight for following leader
+                    + wind
+                    + gravity
+                    + avoid * 3.0; // Obstacles win over flocking
 
                 forces.push(total_force);
             }
@@ -212,25 +258,150 @@ impl Simulation {
 
         // Re-create other boids
         for _ in 1..self.config.num_boids {
-            self.boids.push(Boid::new(&self.config));
+            self.boids.push(Boid::new(&self.config, &mut self.rng));
         }
 
         // Reset leader state
         self.leader = Some(LeaderBird::new(0, &self.config));
     }
 
+    pub fn session(&self) -> Session {
+        Session {
+            wind_x: self.wind_x,
+            boids: self
+                .boids
+                .iter()
+                .filter(|b| !b.is_leader)
+                .map(|b| (b.position, b.velocity))
+                .collect(),
+        }
+    }
+
+    /// Replaces the followers and wind with a saved session, keeping the leader.
+    pub fn restore(&mut self, session: &Session) {
+        self.boids.retain(|b| b.is_leader);
+
+        let room = self.config.max_boids.saturating_sub(self.boids.len());
+        for &(position, velocity) in session.boids.iter().take(room) {
+            let mut boid = Boid::new(&self.config, &mut self.rng);
+            boid.position = position;
+            boid.velocity = velocity.limit(self.config.max_speed);
+            self.boids.push(boid);
+        }
+
+        self.wind_x = session.wind_x;
+        // Sessions saved on a larger terminal may place boids off the canvas
+        self.keep_boids_in_bounds();
+    }
+
+    /// Renders the visible boids as plain text, one line per canvas row.
+    pub fn frame_text(&self, charset: &Charset) -> String {
+        let width = self.config.width as usize;
+        let height = self.config.height as usize;
+        let mut grid = vec![vec![' '; width]; height];
+
+        for boid in self.boids.iter().filter(|b| !b.is_leader) {
+            let x = boid.position.x as usize;
+            let y = boid.position.y as usize;
+            if let Some(cell) = grid.get_mut(y).and_then(|row| row.get_mut(x)) {
+                *cell = boid.get_direction_char(charset);
+            }
+        }
+
+        let mut text = String::new();
+        for row in grid {
+            let line: String = row.into_iter().collect();
+            text.push_str(line.trim_end());
+            text.push('\n');
+        }
+        text
+    }
+
+    /// Adds a boid at `position`, unless the flock is already at `max_boids`.
+    pub fn spawn_boid_at(&mut self, position: Vec2) -> bool {
+        if self.boids.len() >= self.config.max_boids {
+            return false;
+        }
+
+        let mut boid = Boid::new(&self.config, &mut self.rng);
+        boid.position = position;
+        self.boids.push(boid);
+        true
+    }
+
+    pub fn adjust_wind(&mut self, delta: f32) {
+        self.wind_x = (self.wind_x + delta).clamp(-1.0, 1.0);
+    }
+
+    /// Current wind including any gust, in the same -1.0..=1.0 range as `wind_x`.
+    pub fn wind(&self) -> f32 {
+        (self.wind_x + self.gust).clamp(-1.0, 1.0)
+    }
+
+    pub fn toggle_gusts(&mut self) {
+        self.gusts = !self.gusts;
+    }
+
+    fn update_wind(&mut self) {
+        if self.gusts {
+            // Occasionally pick a new gust strength to drift towards
+            if self.rng.gen_bool(0.02) {
+                self.gust_target = self.rng.gen_range(-0.6..0.6);
+            }
+        } else {
+            self.gust_target = 0.0;
+        }
+
+        // Ease towards the target so gusts build and fade smoothly
+        self.gust += (self.gust_target - self.gust) * 0.03;
+    }
+
+    fn wind_force(&self) -> Vec2 {
+        // Full wind pushes with half the steering budget, so boids drift but can still flock
+        Vec2 {
+            x: self.wind() * self.config.max_force * 0.5,
+            y: 0.0,
+        }
+    }
+
+    fn gravity_force(&self) -> Vec2 {
+        // Screen y grows downwards
+        Vec2 {
+            x: 0.0,
+            y: self.config.gravity * self.config.max_force,
+        }
+    }
+
+    fn obstacle_force(&self, index: usize) -> Vec2 {
+        // Start turning away this many cells before reaching an obstacle
+        let margin = 3.0;
+        let position = self.boids[index].position;
+        let mut steer = Vec2::zero();
+
+        for obstacle in &self.config.obstacles {
+            let away = position - obstacle.closest_point(position);
+            let distance = away.magnitude();
+            if distance > 0.0 && distance < margin {
+                // Push harder the closer the boid gets
+                steer += away.normalize() * ((margin - distance) / margin);
+            }
+        }
+
+        steer.limit(1.0) * self.config.max_force
+    }
+
     pub fn adjust_boid_count_for_size(&mut self, terminal_size: Rect) {
-        let new_config = Config::with_terminal_size(terminal_size);
-        let target_count = new_config.num_boids;
         let current_count = self.boids.len();
 
-        // Update config (other parameters will also be updated except boid count)
-        self.config = new_config;
+        // Refit the config; user settings such as density are kept
+        self.config.fit_to_terminal(terminal_size);
+        let target_count = self.config.num_boids;
+        self.keep_boids_in_bounds();
 
         if target_count > current_count {
             // Increase boid count
             for _ in current_count..target_count {
-                self.boids.push(Boid::new(&self.config));
+                self.boids.push(Boid::new(&self.config, &mut self.rng));
             }
         } else if target_count < current_count {
             // Decrease boid count, but protect leader bird (index 0)
@@ -241,11 +412,21 @@ impl Simulation {
 
         // Update leader target
         if let Some(ref mut leader) = self.leader {
-            leader.target_x = match leader.direction {
-                PatrolDirection::ToRight => self.config.width * 0.9,
-                PatrolDirection::ToLeft => self.config.width * 0.1,
-            };
-            leader.sine_amplitude = self.config.height * 0.3;
+            leader.target_x = turnaround_x(&self.config, leader.direction);
+            leader.sine_amplitude = self.config.height * self.config.patrol_amplitude;
+        }
+    }
+
+    /// Resizes the world without changing the flock, pulling stray boids back inside.
+    pub fn set_bounds(&mut self, width: f32, height: f32) {
+        self.config.width = width;
+        self.config.height = height;
+        self.keep_boids_in_bounds();
+    }
+
+    fn keep_boids_in_bounds(&mut self) {
+        for boid in &mut self.boids {
+            boid.bounce_off_boundaries(&self.config);
         }
     }
 
@@ -259,17 +440,18 @@ impl Simulation {
                 leader.sine_time += leader.sine_frequency;
 
                 // Check if reached boundary and need to switch direction
+                let x = leader_boid.position.x;
                 match leader.direction {
                     PatrolDirection::ToRight => {
-                        if leader_boid.position.x >= self.config.width * 0.9 {
+                        if x >= turnaround_x(&self.config, PatrolDirection::ToRight) {
                             leader.direction = PatrolDirection::ToLeft;
-                            leader.target_x = self.config.width * 0.1;
+                            leader.target_x = turnaround_x(&self.config, PatrolDirection::ToLeft);
                         }
                     }
                     PatrolDirection::ToLeft => {
-                        if leader_boid.position.x <= self.config.width * 0.1 {
+                        if x <= turnaround_x(&self.config, PatrolDirection::ToLeft) {
                             leader.direction = PatrolDirection::ToRight;
-                            leader.target_x = self.config.width * 0.9;
+                            leader.target_x = turnaround_x(&self.config, PatrolDirection::ToRight);
                         }
                     }
                 }
@@ -326,3 +508,138 @@ impl Simulation {
         Vec2::zero()
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::{Edges, Obstacle};
+
+    // Regenerate by printing `frame_text` after an intended change to the flocking rules
+    const GOLDEN_FRAMES: &[(&str, bool, &[&str])] = &[
+        (
+            "calm",
+            false,
+            &[
+                "",
+                "    /     /",
+                "",
+                "      <    /  <",
+                "",
+                "   <",
+                "        /",
+                "            < <",
+                "",
+                "              /",
+                "      < <",
+                "     <      <",
+            ],
+        ),
+        (
+            "windy with gusts",
+            true,
+            &[
+                "",
+                "        <   /   <",
+                "",
+                " >",
+                "            <     <",
+                "      <",
+                "",
+                "              /    /",
+                "",
+                "                <",
+                "     <       <",
+                "        <   <",
+            ],
+        ),
+    ];
+
+    #[test]
+    fn seeded_flock_matches_golden_frames() {
+        for (name, windy, expected) in GOLDEN_FRAMES {
+            let terminal_size = Rect::new(0, 0, 40, 12);
+            let mut simulation =
+                Simulation::new_with_size(terminal_size, Some(22), Config::default());
+            if *windy {
+                simulation.gusts = true;
+                simulation.wind_x = 0.5;
+            }
+            for _ in 0..60 {
+                simulation.update();
+            }
+
+            let expected: String = expected.iter().map(|line| format!("{}\n", line)).collect();
+            assert_eq!(simulation.frame_text(&Charset::Ascii), expected, "{}", name);
+        }
+    }
+
+    #[test]
+    fn leader_turns_around_at_the_canvas_edges() {
+        let config = Config {
+            patrol_min_x: 0.0,
+            patrol_max_x: 1.0,
+            ..Config::default()
+        };
+        let mut simulation = Simulation::new_with_size(Rect::new(0, 0, 80, 24), Some(41), config);
+
+        let mut turns = 0;
+        let mut heading_right = true;
+        for _ in 0..1000 {
+            simulation.update();
+            let leader = simulation.leader.as_ref().unwrap();
+            if matches!(leader.direction, PatrolDirection::ToRight) != heading_right {
+                heading_right = !heading_right;
+                turns += 1;
+            }
+        }
+        assert!(turns >= 2, "leader turned {} times", turns);
+    }
+
+    #[test]
+    fn boids_stay_on_the_canvas() {
+        for (width, height) in [(1, 1), (20, 8), (40, 10), (80, 24), (250, 70)] {
+            for edges in [Edges::Bounce, Edges::Wrap] {
+                let config = Config {
+                    edges,
+                    gravity: 1.0,
+                    max_speed: 3.0,
+                    ..Config::default()
+                };
+                let mut simulation =
+                    Simulation::new_with_size(Rect::new(0, 0, width, height), Some(66), config);
+                // A wall overhanging the right, top and bottom edges; boids caught inside
+                // near the right edge are pushed out through its far side
+                let config = &simulation.config;
+                let wall = Obstacle {
+                    x: config.width - 4.0,
+                    y: -1.0,
+                    width: 4.5,
+                    height: config.height + 2.0,
+                };
+                simulation.config.obstacles = vec![wall];
+                simulation.gusts = true;
+                simulation.wind_x = 1.0;
+
+                for tick in 0..500 {
+                    simulation.update();
+                    let (max_x, max_y) = (simulation.config.width, simulation.config.height);
+                    for boid in &simulation.boids {
+                        let Vec2 { x, y } = boid.position;
+                        assert!(
+                            (0.0..=max_x).contains(&x) && (0.0..=max_y).contains(&y),
+                            "{}x{} {:?} tick {}: boid at ({}, {}) outside {}x{}",
+                            width,
+                            height,
+                            edges,
+                            tick,
+                            x,
+                            y,
+                            max_x,
+                            max_y
+                        );
+                    }
+                }
+            }
+        }
+    }
+}
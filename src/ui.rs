@@ -1,29 +1,172 @@
+use crate::boid::{Charset, Vec2};
+use crate::color::{hue_color, shade_color, ColorSupport, Palette};
+use crate::config::{Config, MAX_DENSITY, MIN_DENSITY};
+use crate::session::Session;
 use crate::simulation::Simulation;
 use ratatui::{
-    layout::{Constraint, Direction, Layout, Rect},
+    layout::{Alignment, Constraint, Direction, Layout, Rect},
     style::{Color, Modifier, Style},
     text::{Line, Span, Text},
-    widgets::{Block, Borders, Paragraph},
-    widgets::canvas::Canvas,
+    widgets::{Block, Borders, Clear, Paragraph},
+    widgets::canvas::{Canvas, Points, Rectangle},
     Frame,
 };
-use std::time::{Duration, Instant};
+use std::{
+    fs,
+    time::{Duration, Instant, SystemTime, UNIX_EPOCH},
+};
+
+// Below this the panels no longer fit, so only a warning is shown
+const MIN_TERMINAL_WIDTH: u16 = 40;
+const MIN_TERMINAL_HEIGHT: u16 = 10;
+
+// Redraw rate while nothing moves; key presses still wake the loop immediately
+const IDLE_FRAME_DURATION: Duration = Duration::from_millis(200);
+
+// How long the wind indicator stays up after a change
+const WIND_OSD_DURATION: Duration = Duration::from_secs(1);
+
+// Simulation time per tick with a fixed timestep, matching the default 30 FPS
+const FIXED_STEP: Duration = Duration::from_nanos(33_333_333);
+// A frame that fell further behind than this drops the backlog instead of spiralling
+const MAX_STEPS_PER_FRAME: u32 = 5;
+
+// Key bindings listed in the help overlay
+const CONTROLS: &[(&str, &str)] = &[
+    ("Space/P", "Pause/Resume"),
+    (".", "Step when paused"),
+    ("F", "Toggle FPS"),
+    ("+/-", "Speed"),
+    ("←/→", "Wind"),
+    ("G", "Wind gusts"),
+    ("[/]", "Density"),
+    ("Click", "Add boid"),
+    ("W", "Save frame"),
+    ("S", "Save session"),
+    ("R", "Reset"),
+    ("I", "Stats overlay"),
+    ("?", "Help"),
+    ("Q", "Quit"),
+];
+
+// The few bindings that fit in the side panel on a 24-row terminal; `?` lists the rest
+const PANEL_CONTROLS: &[&str] = &["Space/P", "?", "Q"];
+
+/// Startup settings for [`App`]. Override fields on top of `AppOptions::default()`.
+///
+/// Flock tunables live in `config`; its size-dependent fields are recomputed
+/// from the terminal size.
+#[derive(Debug, Clone)]
+pub struct AppOptions {
+    pub boid_color: Color,
+    pub paused_color: Color,
+    /// Canvas background; `Color::Reset` keeps the terminal's own background.
+    pub bg_color: Color,
+    /// ASCII art drawn behind the flock, one line per row, anchored to the bottom.
+    pub backdrop: Vec<String>,
+    pub charset: Charset,
+    /// Seconds per trip around the color wheel; `None` keeps `boid_color`.
+    pub cycle_period: Option<f32>,
+    /// How far each boid's brightness may stray from the flock color, from 0 (uniform) to 1.
+    pub color_variation: f32,
+    /// Draw boids as Braille dots instead of heading glyphs.
+    pub braille: bool,
+    /// Advance the flock by elapsed time instead of one tick per frame.
+    pub fixed_timestep: bool,
+    /// Rendering without a live terminal; wall-clock readings such as the measured FPS
+    /// are left out so seeded output is reproducible.
+    pub headless: bool,
+    /// Colors are converted to what the terminal supports after each frame is drawn.
+    pub color_support: ColorSupport,
+    /// Flock to restore instead of a random one.
+    pub session: Option<Session>,
+    pub seed: Option<u64>,
+    pub config: Config,
+}
+
+impl Default for AppOptions {
+    fn default() -> Self {
+        Self {
+            boid_color: Palette::default().boid,
+            paused_color: Palette::default().paused,
+            bg_color: Color::Reset,
+            backdrop: Vec::new(),
+            charset: Charset::Ascii,
+            cycle_period: None,
+            color_variation: 0.0,
+            braille: false,
+            fixed_timestep: false,
+            headless: false,
+            color_support: ColorSupport::TrueColor,
+            session: None,
+            seed: None,
+            config: Config::default(),
+        }
+    }
+}
 
 pub struct App {
     pub simulation: Simulation,
     pub paused: bool,
     pub high_fps: bool,
+    pub speed_multiplier: f32,
+    pub show_help: bool,
+    pub show_stats: bool,
+    boid_color: Color,
+    paused_color: Color,
+    bg_color: Color,
+    backdrop: Vec<String>,
+    charset: Charset,
+    cycle_period: Option<f32>,
+    color_variation: f32,
+    braille: bool,
+    fixed_timestep: bool,
+    headless: bool,
+    color_support: ColorSupport,
+    accumulator: Duration,
+    last_tick: Option<Instant>,
+    hue: f32,
+    status: Option<(String, Instant)>,
+    wind_changed_at: Option<Instant>,
+    canvas_area: Rect,
+    too_small: bool,
     last_update: Instant,
     frame_count: u32,
     fps_counter: f32,
 }
 
 impl App {
-    pub fn new(terminal_size: Rect) -> Self {
+    pub fn new(terminal_size: Rect, options: AppOptions) -> Self {
+        let mut simulation = Simulation::new_with_size(terminal_size, options.seed, options.config);
+        if let Some(session) = &options.session {
+            simulation.restore(session);
+        }
+
         Self {
-            simulation: Simulation::new_with_size(terminal_size),
+            simulation,
             paused: false,
             high_fps: false,
+            speed_multiplier: 1.0,
+            show_help: false,
+            show_stats: false,
+            boid_color: options.boid_color,
+            paused_color: options.paused_color,
+            bg_color: options.bg_color,
+            backdrop: options.backdrop,
+            charset: options.charset,
+            cycle_period: options.cycle_period,
+            color_variation: options.color_variation,
+            braille: options.braille,
+            fixed_timestep: options.fixed_timestep,
+            headless: options.headless,
+            color_support: options.color_support,
+            accumulator: Duration::ZERO,
+            last_tick: None,
+            hue: 0.0,
+            status: None,
+            wind_changed_at: None,
+            canvas_area: Rect::default(),
+            too_small: false,
             last_update: Instant::now(),
             frame_count: 0,
             fps_counter: 0.0,
@@ -31,12 +174,21 @@ impl App {
     }
 
     pub fn update(&mut self) {
-        if !self.paused {
-            self.simulation.update();
+        self.update_at(Instant::now());
+    }
+
+    // Takes the clock reading as an argument so tests can drive time by hand
+    fn update_at(&mut self, now: Instant) {
+        if self.paused || self.too_small {
+            // Don't count the time spent stopped once the flock moves again
+            self.last_tick = None;
+        } else if self.fixed_timestep {
+            self.catch_up(now);
+        } else {
+            self.tick();
         }
         
         self.frame_count += 1;
-        let now = Instant::now();
         if now.duration_since(self.last_update) >= Duration::from_secs(1) {
             self.fps_counter = self.frame_count as f32;
             self.frame_count = 0;
@@ -44,29 +196,229 @@ impl App {
         }
     }
 
+    /// Short summary of the current state for the terminal window title.
+    pub fn title(&self) -> String {
+        let boids = self.simulation.boids.len();
+        if self.paused {
+            format!("tamama — paused, {} boids", boids)
+        } else {
+            format!("tamama — {} boids", boids)
+        }
+    }
+
+    /// Advances a paused simulation by exactly one tick.
+    pub fn step(&mut self) {
+        if self.paused && !self.too_small {
+            self.tick();
+            self.last_tick = None;
+        }
+    }
+
+    // Runs as many fixed ticks as the real time since the last frame covers
+    fn catch_up(&mut self, now: Instant) {
+        if let Some(last_tick) = self.last_tick {
+            self.accumulator += now.duration_since(last_tick).mul_f32(self.speed_multiplier);
+        }
+        self.last_tick = Some(now);
+
+        let mut steps = 0;
+        while self.accumulator >= FIXED_STEP {
+            if steps == MAX_STEPS_PER_FRAME {
+                self.accumulator = Duration::ZERO;
+                break;
+            }
+            self.tick();
+            self.accumulator -= FIXED_STEP;
+            steps += 1;
+        }
+    }
+
+    fn tick(&mut self) {
+        self.simulation.update();
+
+        // Advance by simulation time, one tick being a 30 FPS frame
+        if let Some(period) = self.cycle_period {
+            self.hue = (self.hue + 360.0 / (period * 30.0)) % 360.0;
+        }
+    }
+
     pub fn toggle_pause(&mut self) {
         self.paused = !self.paused;
+        // The event loop stops calling update while paused, so forget the last tick here;
+        // otherwise the whole pause would count as backlog on resume
+        self.last_tick = None;
     }
 
     pub fn toggle_fps(&mut self) {
         self.high_fps = !self.high_fps;
     }
 
+    pub fn toggle_help(&mut self) {
+        self.show_help = !self.show_help;
+    }
+
+    pub fn toggle_stats(&mut self) {
+        self.show_stats = !self.show_stats;
+    }
+
+    pub fn speed_up(&mut self) {
+        self.speed_multiplier = (self.speed_multiplier * 2.0).min(4.0);
+    }
+
+    pub fn slow_down(&mut self) {
+        self.speed_multiplier = (self.speed_multiplier / 2.0).max(0.25);
+    }
+
+    /// True while the flock is frozen, so the event loop can back off.
+    pub fn is_idle(&self) -> bool {
+        self.paused || self.too_small
+    }
+
+    pub fn frame_duration(&self) -> Duration {
+        if self.is_idle() {
+            return IDLE_FRAME_DURATION;
+        }
+
+        let target_fps = if self.high_fps { 60.0 } else { 30.0 };
+        Duration::from_secs_f32(1.0 / (target_fps * self.speed_multiplier))
+    }
+
     pub fn reset(&mut self) {
         self.simulation.reset();
     }
 
+    pub fn adjust_wind(&mut self, delta: f32) {
+        self.simulation.adjust_wind(delta);
+        self.wind_changed_at = Some(Instant::now());
+    }
+
+    pub fn toggle_gusts(&mut self) {
+        self.simulation.toggle_gusts();
+    }
+
+    pub fn adjust_density(&mut self, delta: f32) {
+        let config = &mut self.simulation.config;
+        config.density = (config.density + delta).clamp(MIN_DENSITY, MAX_DENSITY);
+
+        let terminal_size = terminal_size_for_canvas(config.width, config.height);
+        self.simulation.adjust_boid_count_for_size(terminal_size);
+    }
+
+    /// Spawns a boid under a mouse click, ignoring clicks outside the canvas.
+    pub fn click(&mut self, column: u16, row: u16) {
+        if self.too_small {
+            return;
+        }
+
+        // Canvas contents sit inside a one-cell border
+        let inner_left = self.canvas_area.x + 1;
+        let inner_top = self.canvas_area.y + 1;
+        let inner_right = self.canvas_area.right().saturating_sub(1);
+        let inner_bottom = self.canvas_area.bottom().saturating_sub(1);
+
+        if column < inner_left || column >= inner_right || row < inner_top || row >= inner_bottom {
+            return;
+        }
+
+        let position = Vec2 {
+            x: (column - inner_left) as f32 + 0.5,
+            y: (row - inner_top) as f32 + 0.5,
+        };
+        self.simulation.spawn_boid_at(position);
+    }
+
+    pub fn export_frame(&mut self) {
+        let text = self.simulation.frame_text(&self.charset);
+        self.save_timestamped("tamama-frame", text);
+    }
+
+    pub fn save_session(&mut self) {
+        let text = self.simulation.session().to_text();
+        self.save_timestamped("tamama-session", text);
+    }
+
+    // Writes `contents` to `<prefix>-<unix time>.txt` and reports the outcome in the status line
+    fn save_timestamped(&mut self, prefix: &str, contents: String) {
+        let timestamp = SystemTime::now()
+            .duration_since(UNIX_EPOCH)
+            .map(|d| d.as_secs())
+            .unwrap_or_default();
+        let path = format!("{}-{}.txt", prefix, timestamp);
+
+        let message = match fs::write(&path, contents) {
+            Ok(()) => format!("Saved {}", path),
+            Err(err) => format!("Save failed: {}", err),
+        };
+        self.show_status(message);
+    }
+
+    /// Shows `message` in the statistics panel for a few seconds.
+    pub fn show_status(&mut self, message: String) {
+        self.status = Some((message, Instant::now()));
+    }
+
+    /// Applies options changed while running, keeping the current flock and canvas size.
+    ///
+    /// The seed only matters at startup and is ignored.
+    pub fn apply_options(&mut self, options: AppOptions) {
+        self.boid_color = options.boid_color;
+        self.paused_color = options.paused_color;
+        self.bg_color = options.bg_color;
+        self.backdrop = options.backdrop;
+        self.charset = options.charset;
+        self.cycle_period = options.cycle_period;
+        self.color_variation = options.color_variation;
+        self.braille = options.braille;
+        self.fixed_timestep = options.fixed_timestep;
+        self.color_support = options.color_support;
+
+        let config = &self.simulation.config;
+        let terminal_size = terminal_size_for_canvas(config.width, config.height);
+        self.simulation.config = options.config;
+        self.simulation.adjust_boid_count_for_size(terminal_size);
+    }
+
     pub fn render(&mut self, f: &mut Frame) {
+        let size = f.size();
+        self.too_small = size.width < MIN_TERMINAL_WIDTH || size.height < MIN_TERMINAL_HEIGHT;
+        if self.too_small {
+            self.render_too_small(f);
+            return;
+        }
+
         let chunks = Layout::default()
             .direction(Direction::Horizontal)
             .constraints([Constraint::Percentage(75), Constraint::Percentage(25)])
             .split(f.size());
 
         let canvas_area = chunks[0];
+        self.canvas_area = canvas_area;
         self.update_simulation_bounds(canvas_area);
 
         self.render_simulation(f, canvas_area);
         self.render_info_panel(f, chunks[1]);
+
+        if self.show_stats {
+            self.render_stats_overlay(f, canvas_area);
+        }
+        if self
+            .wind_changed_at
+            .is_some_and(|at| at.elapsed() < WIND_OSD_DURATION)
+        {
+            self.render_wind_osd(f, canvas_area);
+        }
+        if self.show_help {
+            self.render_help(f);
+        }
+
+        // Done on the finished buffer so every widget is covered
+        if self.color_support != ColorSupport::TrueColor {
+            for cell in &mut f.buffer_mut().content {
+                let (fg, bg) = (cell.fg, cell.bg);
+                cell.set_fg(self.color_support.adapt(fg));
+                cell.set_bg(self.color_support.adapt(bg));
+            }
+        }
     }
 
     fn update_simulation_bounds(&mut self, area: Rect) {
@@ -79,18 +431,11 @@ impl App {
         
         if width_diff > 5.0 || height_diff > 3.0 {
             // Create a virtual terminal size Rect to recalculate boid count
-            let virtual_terminal_size = Rect {
-                x: 0,
-                y: 0,
-                width: (canvas_width / 0.75) as u16, // Reverse calculate terminal width
-                height: canvas_height as u16,
-            };
-            
+            let virtual_terminal_size = terminal_size_for_canvas(canvas_width, canvas_height);
             self.simulation.adjust_boid_count_for_size(virtual_terminal_size);
         } else {
             // Only update boundaries, don't adjust boid count
-            self.simulation.config.width = canvas_width;
-            self.simulation.config.height = canvas_height;
+            self.simulation.set_bounds(canvas_width, canvas_height);
         }
     }
 
@@ -104,38 +449,91 @@ impl App {
             )
             .x_bounds([0.0, self.simulation.config.width.into()])
             .y_bounds([0.0, self.simulation.config.height.into()])
+            .background_color(self.bg_color)
             .paint(|ctx| {
-                for boid in &self.simulation.boids {
-                    // Hide leader bird, don't display
-                    if boid.is_leader {
-                        continue;
+                let height = self.simulation.config.height;
+                for obstacle in &self.simulation.config.obstacles {
+                    ctx.draw(&Rectangle {
+                        x: obstacle.x.into(),
+                        y: (height - obstacle.y - obstacle.height).into(),
+                        width: obstacle.width.into(),
+                        height: obstacle.height.into(),
+                        color: Color::DarkGray,
+                    });
+                }
+
+                let color = if self.paused {
+                    self.paused_color
+                } else if self.cycle_period.is_some() {
+                    hue_color(self.hue)
+                } else {
+                    self.boid_color
+                };
+
+                // Hide leader bird, don't display
+                let followers = self.simulation.boids.iter().filter(|b| !b.is_leader);
+
+                if self.braille {
+                    // Sub-cell dots trade the heading glyphs for smooth motion
+                    let coords: Vec<(f64, f64)> = followers
+                        .map(|b| (b.position.x.into(), (height - b.position.y).into()))
+                        .collect();
+                    ctx.draw(&Points {
+                        coords: &coords,
+                        color,
+                    });
+                    return;
+                }
+
+                for boid in followers {
+                    let mut style = Style::default().fg(color);
+                    if self.color_variation > 0.0 {
+                        if let Color::Rgb(..) = color {
+                            style = style.fg(shade_color(color, boid.shade * self.color_variation));
+                        } else if boid.shade > 0.0 {
+                            // Named colors can't be shaded, so set half the flock apart in bold
+                            style = style.add_modifier(Modifier::BOLD);
+                        }
                     }
-                    
-                    let color = if self.paused {
-                        Color::Gray
-                    } else {
-                        Color::Green
-                    };
-                    
+
                     ctx.print(
                         boid.position.x.into(),
                         (self.simulation.config.height - boid.position.y).into(), 
-                        Span::styled(
-                            boid.get_direction_char().to_string(),
-                            Style::default().fg(color)
-                        )
+                        Span::styled(boid.get_direction_char(&self.charset).to_string(), style)
                     );
                 }
             });
 
+        self.render_backdrop(f, area);
         f.render_widget(canvas, area);
     }
 
+    // Drawn straight into the buffer before the canvas. The canvas only overwrites cells it
+    // paints, so the art stays behind every boid and obstacle, whether glyphs or Braille dots.
+    fn render_backdrop(&self, f: &mut Frame, canvas_area: Rect) {
+        // Inside the canvas border, with the last line on the bottom row
+        let inner_height = canvas_area.height.saturating_sub(2);
+        let height = (self.backdrop.len() as u16).min(inner_height);
+        let backdrop_area = Rect {
+            x: canvas_area.x + 1,
+            y: canvas_area.y + 1 + inner_height - height,
+            width: canvas_area.width.saturating_sub(2),
+            height,
+        };
+
+        let lines: Vec<Line> = self.backdrop[self.backdrop.len() - height as usize..]
+            .iter()
+            .map(|line| Line::from(line.as_str()))
+            .collect();
+        let paragraph = Paragraph::new(lines).style(Style::default().fg(Color::DarkGray));
+        f.render_widget(paragraph, backdrop_area);
+    }
+
     fn render_info_panel(&self, f: &mut Frame, area: Rect) {
         let chunks = Layout::default()
             .direction(Direction::Vertical)
             .constraints([
-                Constraint::Length(8),  
+                Constraint::Length(PANEL_CONTROLS.len() as u16 + 7),
                 Constraint::Length(10), 
                 Constraint::Min(0),     
             ])
@@ -150,7 +548,7 @@ impl App {
         let status = if self.paused { "PAUSED" } else { "RUNNING" };
         let fps_mode = if self.high_fps { "60 FPS" } else { "30 FPS" };
         
-        let text = Text::from(vec![
+        let mut text = Text::from(vec![
             Line::from(vec![
                 Span::styled("Status: ", Style::default().fg(Color::Yellow)),
                 Span::styled(status, Style::default().fg(if self.paused { Color::Red } else { Color::Green })),
@@ -159,13 +557,16 @@ impl App {
                 Span::styled("FPS: ", Style::default().fg(Color::Yellow)),
                 Span::styled(fps_mode, Style::default().fg(Color::Cyan)),
             ]),
+            Line::from(vec![
+                Span::styled("Speed: ", Style::default().fg(Color::Yellow)),
+                Span::styled(format!("{:.2}x", self.speed_multiplier), Style::default().fg(Color::Cyan)),
+            ]),
             Line::from(""),
             Line::from(Span::styled("Controls:", Style::default().fg(Color::White).add_modifier(Modifier::BOLD))),
-            Line::from("Space - Pause/Resume"),
-            Line::from("F - Toggle FPS"),
-            Line::from("R - Reset"),
-            Line::from("Q - Quit"),
         ]);
+        for (key, action) in CONTROLS.iter().filter(|(key, _)| PANEL_CONTROLS.contains(key)) {
+            text.lines.push(Line::from(format!("{} - {}", key, action)));
+        }
 
         let paragraph = Paragraph::new(text)
             .block(
@@ -178,25 +579,173 @@ impl App {
         f.render_widget(paragraph, area);
     }
 
+    fn render_stats_overlay(&self, f: &mut Frame, canvas_area: Rect) {
+        let label = Style::default().fg(Color::Yellow);
+        let lines: Vec<Line> = [
+            ("Boids", self.simulation.boids.len().to_string()),
+            ("FPS", format!("{:.1}", self.fps_counter)),
+            ("Density", format!("{:.2}x", self.simulation.config.density)),
+            ("Wind", format!("{:+.1}", self.simulation.wind())),
+        ]
+        .into_iter()
+        .filter(|(name, _)| !(self.headless && *name == "FPS"))
+        .map(|(name, value)| {
+            Line::from(vec![
+                Span::styled(format!(" {:<8}", name), label),
+                Span::raw(value),
+            ])
+        })
+        .collect();
+
+        // Top-right corner, just inside the canvas border
+        let width = 18.min(canvas_area.width.saturating_sub(2));
+        let height = (lines.len() as u16).min(canvas_area.height.saturating_sub(2));
+        let overlay = Rect {
+            x: canvas_area.right().saturating_sub(width + 1),
+            y: canvas_area.y + 1,
+            width,
+            height,
+        };
+
+        let paragraph =
+            Paragraph::new(lines).style(Style::default().fg(Color::White).bg(Color::DarkGray));
+        f.render_widget(Clear, overlay);
+        f.render_widget(paragraph, overlay);
+    }
+
+    fn render_wind_osd(&self, f: &mut Frame, canvas_area: Rect) {
+        // One arrow per fifth of full strength, like a volume bar
+        let wind = self.simulation.wind_x;
+        let arrows = ((wind.abs() * 5.0).round() as usize).clamp(1, 5);
+        let text = if wind.abs() < 0.05 {
+            " Wind: calm ".to_string()
+        } else if wind > 0.0 {
+            format!(" Wind: {} {:.1} ", "→".repeat(arrows), wind.abs())
+        } else {
+            format!(" Wind: {} {:.1} ", "←".repeat(arrows), wind.abs())
+        };
+
+        // Top center, just inside the canvas border
+        let width = (text.chars().count() as u16).min(canvas_area.width.saturating_sub(2));
+        let osd = Rect {
+            x: canvas_area.x + (canvas_area.width - width) / 2,
+            y: canvas_area.y + 1,
+            width,
+            height: 1.min(canvas_area.height.saturating_sub(2)),
+        };
+
+        let paragraph =
+            Paragraph::new(text).style(Style::default().fg(Color::White).bg(Color::DarkGray));
+        f.render_widget(Clear, osd);
+        f.render_widget(paragraph, osd);
+    }
+
+    fn render_too_small(&self, f: &mut Frame) {
+        let area = f.size();
+        let message = vec![
+            Line::from("Terminal too small"),
+            Line::from(format!(
+                "Need {}x{}",
+                MIN_TERMINAL_WIDTH, MIN_TERMINAL_HEIGHT
+            )),
+        ];
+
+        // Vertically center the two lines where there is room for them
+        let height = (message.len() as u16).min(area.height);
+        let centered = Rect {
+            y: area.y + (area.height - height) / 2,
+            height,
+            ..area
+        };
+
+        let paragraph = Paragraph::new(message)
+            .style(Style::default().fg(Color::Yellow))
+            .alignment(Alignment::Center);
+        f.render_widget(paragraph, centered);
+    }
+
+    fn render_help(&self, f: &mut Frame) {
+        let config = &self.simulation.config;
+        let heading = Style::default()
+            .fg(Color::White)
+            .add_modifier(Modifier::BOLD);
+        let label = Style::default().fg(Color::Yellow);
+
+        let mut lines = vec![Line::from(Span::styled("Controls", heading))];
+        for (key, action) in CONTROLS {
+            lines.push(Line::from(vec![
+                Span::styled(format!("{:<10}", key), label),
+                Span::raw(*action),
+            ]));
+        }
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled("Settings", heading)));
+        for (name, value) in [
+            ("Speed", format!("{:.2}x", self.speed_multiplier)),
+            ("Density", format!("{:.2}x", config.density)),
+            ("Wind", format!("{:+.1}", self.simulation.wind())),
+            ("Boids", self.simulation.boids.len().to_string()),
+        ] {
+            lines.push(Line::from(vec![
+                Span::styled(format!("{:<10}", name), label),
+                Span::raw(value),
+            ]));
+        }
+
+        // Center the box, shrinking it to fit small terminals
+        let area = f.size();
+        let width = 36.min(area.width);
+        let height = (lines.len() as u16 + 2).min(area.height);
+        let popup = Rect {
+            x: area.x + (area.width - width) / 2,
+            y: area.y + (area.height - height) / 2,
+            width,
+            height,
+        };
+
+        let paragraph = Paragraph::new(lines).block(
+            Block::default()
+                .title("Help (? to close)")
+                .borders(Borders::ALL)
+                .border_style(Style::default().fg(Color::Cyan)),
+        );
+
+        f.render_widget(Clear, popup);
+        f.render_widget(paragraph, popup);
+    }
+
     fn render_stats(&self, f: &mut Frame, area: Rect) {
         let avg_speed: f32 = self.simulation.boids.iter()
             .map(|b| b.velocity.magnitude())
             .sum::<f32>() / self.simulation.boids.len() as f32;
 
-        let text = Text::from(vec![
+        let mut lines = vec![
             Line::from(vec![
                 Span::styled("Boids: ", Style::default().fg(Color::Yellow)),
                 Span::styled(self.simulation.boids.len().to_string(), Style::default().fg(Color::White)),
             ]),
-            Line::from(vec![
+        ];
+        // Measured against the wall clock, so it would differ between headless runs
+        if !self.headless {
+            lines.push(Line::from(vec![
                 Span::styled("Actual FPS: ", Style::default().fg(Color::Yellow)),
                 Span::styled(format!("{:.1}", self.fps_counter), Style::default().fg(Color::White)),
-            ]),
-            Line::from(vec![
-                Span::styled("Avg Speed: ", Style::default().fg(Color::Yellow)),
-                Span::styled(format!("{:.2}", avg_speed), Style::default().fg(Color::White)),
-            ]),
-        ]);
+            ]));
+        }
+        lines.push(Line::from(vec![
+            Span::styled("Avg Speed: ", Style::default().fg(Color::Yellow)),
+            Span::styled(format!("{:.2}", avg_speed), Style::default().fg(Color::White)),
+        ]));
+
+        // Show the last status message for a few seconds
+        if let Some((message, at)) = &self.status {
+            if at.elapsed() < Duration::from_secs(3) {
+                lines.push(Line::from(""));
+                lines.push(Line::from(Span::styled(message.as_str(), Style::default().fg(Color::Cyan))));
+            }
+        }
+
+        let text = Text::from(lines);
 
         let paragraph = Paragraph::new(text)
             .block(
@@ -234,6 +783,14 @@ impl App {
                 Span::styled("Max Force: ", Style::default().fg(Color::Yellow)),
                 Span::styled(format!("{:.2}", config.max_force), Style::default().fg(Color::White)),
             ]),
+            Line::from(vec![
+                Span::styled("Density: ", Style::default().fg(Color::Yellow)),
+                Span::styled(format!("{:.2}x", config.density), Style::default().fg(Color::White)),
+            ]),
+            Line::from(vec![
+                Span::styled("Wind: ", Style::default().fg(Color::Yellow)),
+                Span::styled(format!("{:+.1}", self.simulation.wind()), Style::default().fg(Color::White)),
+            ]),
         ]);
 
         let paragraph = Paragraph::new(text)
@@ -246,4 +803,79 @@ impl App {
 
         f.render_widget(paragraph, area);
     }
-}
\ No newline at end of file
+}
+
+// Reverse the 75% canvas split so Config::fit_to_terminal sees the same canvas
+fn terminal_size_for_canvas(canvas_width: f32, canvas_height: f32) -> Rect {
+    Rect {
+        x: 0,
+        y: 0,
+        width: (canvas_width / 0.75) as u16,
+        height: canvas_height as u16,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    // Scaling by the speed goes through f32 seconds, so whole steps can come up a few ns short
+    const SLACK: Duration = Duration::from_millis(1);
+
+    // One degree of hue per tick, so `hue` counts the ticks run
+    fn fixed_timestep_app() -> App {
+        let options = AppOptions {
+            fixed_timestep: true,
+            cycle_period: Some(12.0),
+            seed: Some(23),
+            ..AppOptions::default()
+        };
+        App::new(Rect::new(0, 0, 80, 24), options)
+    }
+
+    #[test]
+    fn fixed_timestep_runs_one_tick_per_step_of_elapsed_time() {
+        let mut app = fixed_timestep_app();
+        let start = Instant::now();
+
+        app.update_at(start);
+        assert_eq!(app.hue, 0.0);
+        app.update_at(start + FIXED_STEP * 3 + SLACK);
+        assert_eq!(app.hue, 3.0);
+
+        // At half speed two more steps of time give one tick
+        app.slow_down();
+        app.update_at(start + FIXED_STEP * 5 + SLACK * 2);
+        assert_eq!(app.hue, 4.0);
+    }
+
+    #[test]
+    fn fixed_timestep_drops_a_large_backlog() {
+        let mut app = fixed_timestep_app();
+        let start = Instant::now();
+
+        app.update_at(start);
+        app.update_at(start + Duration::from_secs(10));
+        assert_eq!(app.hue, MAX_STEPS_PER_FRAME as f32);
+        assert_eq!(app.accumulator, Duration::ZERO);
+    }
+
+    #[test]
+    fn fixed_timestep_skips_the_time_spent_paused() {
+        let mut app = fixed_timestep_app();
+        let start = Instant::now();
+
+        app.update_at(start);
+        // The event loop doesn't call update while paused
+        app.toggle_pause();
+        app.step();
+        app.toggle_pause();
+        assert_eq!(app.hue, 1.0);
+
+        let resumed = start + Duration::from_secs(10);
+        app.update_at(resumed);
+        assert_eq!(app.hue, 1.0);
+        app.update_at(resumed + FIXED_STEP + SLACK);
+        assert_eq!(app.hue, 2.0);
+    }
+}
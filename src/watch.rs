@@ -0,0 +1,46 @@
+use std::{
+    fs,
+    path::{Path, PathBuf},
+    time::{Duration, Instant, SystemTime},
+};
+
+// Edits can wait a moment; stat-ing the file every frame would be wasteful
+const CHECK_INTERVAL: Duration = Duration::from_secs(1);
+
+/// Notices changes to a file's modification time, checking at most once per second.
+pub struct FileWatcher {
+    path: PathBuf,
+    modified: Option<SystemTime>,
+    last_check: Instant,
+}
+
+impl FileWatcher {
+    pub fn new(path: PathBuf) -> Self {
+        let modified = modified_time(&path);
+        Self {
+            path,
+            modified,
+            last_check: Instant::now(),
+        }
+    }
+
+    pub fn changed(&mut self) -> bool {
+        if self.last_check.elapsed() < CHECK_INTERVAL {
+            return false;
+        }
+        self.last_check = Instant::now();
+
+        let modified = modified_time(&self.path);
+        if modified == self.modified {
+            return false;
+        }
+        self.modified = modified;
+
+        // Editors may briefly remove the file while saving; wait for it to come back
+        modified.is_some()
+    }
+}
+
+fn modified_time(path: &Path) -> Option<SystemTime> {
+    fs::metadata(path).and_then(|m| m.modified()).ok()
+}